@@ -0,0 +1,338 @@
+package si
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// bindSources lists the struct tags understood by Bind, in the order
+// they are applied. Later sources override earlier ones.
+var bindSources = []string{"path", "query", "header", "cookie", "form"}
+
+// FieldError describes a request value that could not be bound
+// to a struct field.
+type FieldError struct {
+	// Field is the parameter name taken from the struct tag
+	Field string
+	// Source is where the value came from: path, query, header, cookie,
+	// form, json or body
+	Source string
+	// Value is the raw value that failed to parse
+	Value string
+	// Err is the parse error
+	Err error
+}
+
+// Error implements the error interface
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("%s: %v", e.Source, e.Err)
+	}
+
+	return fmt.Sprintf("%s parameter %q: %v", e.Source, e.Field, e.Err)
+}
+
+// Unwrap returns the parse error
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// BindError is returned by Bind and lists every field that failed to bind.
+// DefaultErrorHandler renders it as a 400 response.
+type BindError struct {
+	Errors []*FieldError
+}
+
+// Error implements the error interface
+func (e *BindError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Error()
+	}
+
+	return "bind: " + strings.Join(msgs, "; ")
+}
+
+// Details returns a JSON-friendly description of the failed fields
+func (e *BindError) Details() []Map {
+	details := make([]Map, len(e.Errors))
+	for i, fe := range e.Errors {
+		details[i] = Map{
+			"field":   fe.Field,
+			"source":  fe.Source,
+			"message": fe.Err.Error(),
+		}
+	}
+
+	return details
+}
+
+// Unwrap returns the field errors, so errors.Is and errors.As reach
+// the parse errors
+func (e *BindError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, fe := range e.Errors {
+		errs[i] = fe
+	}
+
+	return errs
+}
+
+func (e *BindError) add(fe *FieldError) {
+	e.Errors = append(e.Errors, fe)
+}
+
+// Bind populates the struct pointed to by dst from the request.
+//
+// The body is decoded first with the decoder registered for the request
+// Content-Type, so a JSON body uses the regular `json` tags. Fields are
+// then filled from the `path`, `query`, `header`, `cookie` and `form` tags:
+//
+//	type Request struct {
+//		ID     int       `path:"id"`
+//		Page   int       `query:"page"`
+//		Tags   []string  `query:"tag"`
+//		Tenant string    `header:"X-Tenant"`
+//		Since  time.Time `query:"since"`
+//		Name   string    `json:"name"`
+//	}
+//
+// Missing values leave the field untouched. Values that cannot be parsed
+// are collected into a *BindError.
+func (ctx *Context) Bind(dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("si: Bind requires a non-nil pointer to a struct")
+	}
+
+	errs := &BindError{}
+
+	if !ctx.IsForm() && !ctx.IsMultipartForm() {
+		if decoder := ctx.router.decoderFor(ctx.ContentType()); decoder != nil {
+			body, err := ctx.GetRawContent()
+			if err != nil {
+				return err
+			}
+			if len(body) > 0 {
+				if err := decoder.Decode(bytes.NewReader(body), dst); err != nil {
+					errs.add(bodyFieldError(err))
+				}
+			}
+		}
+	}
+
+	b := binder{ctx: ctx, query: ctx.Request.URL.Query()}
+	if ctx.IsForm() || ctx.IsMultipartForm() {
+		form, err := ctx.GetFormData()
+		if err != nil {
+			errs.add(&FieldError{Source: "form", Err: err})
+		}
+		b.form = form
+	}
+	b.bindStruct(rv.Elem(), errs)
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// binder resolves tagged values from a single request
+type binder struct {
+	ctx   *Context
+	query url.Values
+	form  url.Values
+}
+
+func (b binder) bindStruct(v reflect.Value, errs *BindError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			b.bindStruct(fv, errs)
+			continue
+		}
+		if !fv.CanSet() {
+			continue
+		}
+
+		for _, source := range bindSources {
+			name, ok := sf.Tag.Lookup(source)
+			if !ok || name == "" || name == "-" {
+				continue
+			}
+
+			values := b.values(source, name)
+			if len(values) == 0 {
+				continue
+			}
+
+			if err := setField(fv, values); err != nil {
+				errs.add(&FieldError{
+					Field:  name,
+					Source: source,
+					Value:  strings.Join(values, ","),
+					Err:    err,
+				})
+			}
+		}
+	}
+}
+
+func (b binder) values(source, name string) []string {
+	switch source {
+	case "path":
+		if value := chi.URLParam(b.ctx.Request, name); value != "" {
+			return []string{value}
+		}
+	case "query":
+		return b.query[name]
+	case "header":
+		return b.ctx.Request.Header.Values(name)
+	case "cookie":
+		if cookie, err := b.ctx.Request.Cookie(name); err == nil {
+			return []string{cookie.Value}
+		}
+	case "form":
+		return b.form[name]
+	}
+
+	return nil
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	textType     = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// setField parses values into v. Slices receive every value,
+// other kinds use the first one.
+func setField(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Pointer {
+		elem := reflect.New(v.Type().Elem())
+		if err := setField(elem.Elem(), values); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	if v.Kind() == reflect.Slice && !reflect.PointerTo(v.Type()).Implements(textType) {
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+
+	return setValue(v, values[0])
+}
+
+// setValue parses a single raw value into v
+func setValue(v reflect.Value, raw string) error {
+	if v.Kind() == reflect.Pointer {
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	switch v.Type() {
+	case timeType:
+		return setTime(v, raw)
+	case durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(raw))
+	}
+
+	invalid := func() error {
+		return fmt.Errorf("invalid %s value %q", v.Type(), raw)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return invalid()
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return invalid()
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return invalid()
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return invalid()
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+
+	return nil
+}
+
+// setTime accepts RFC 3339 timestamps and plain dates
+func setTime(v reflect.Value, raw string) error {
+	for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			v.Set(reflect.ValueOf(t))
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid time %q, expected RFC 3339 or YYYY-MM-DD", raw)
+}
+
+// bodyFieldError converts a body decoding error into a FieldError
+func bodyFieldError(err error) *FieldError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &FieldError{
+			Field:  typeErr.Field,
+			Source: "json",
+			Value:  typeErr.Value,
+			Err:    fmt.Errorf("cannot use %s as %s", typeErr.Value, typeErr.Type),
+		}
+	}
+
+	return &FieldError{Source: "body", Err: err}
+}
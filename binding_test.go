@@ -0,0 +1,169 @@
+package si
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type level int
+
+func (l *level) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("unknown level")
+	}
+	return nil
+}
+
+type Paging struct {
+	Page  int `query:"page"`
+	Limit int `query:"limit"`
+}
+
+type bindRequest struct {
+	Paging
+	Org     int           `path:"org"`
+	Tags    []string      `query:"tag"`
+	IDs     []int64       `query:"id"`
+	Since   time.Time     `query:"since"`
+	Timeout time.Duration `query:"timeout"`
+	Active  *bool         `query:"active"`
+	Level   level         `query:"level"`
+	Ratio   float32       `query:"ratio"`
+	Tenant  string        `header:"X-Tenant"`
+	Session string        `cookie:"session"`
+	Name    string        `json:"name"`
+	Email   string        `json:"email" form:"email"`
+	Ignored string        `query:"-"`
+	private string        `query:"private"`
+}
+
+// bind serves req on a route with an {org} parameter and binds it
+func bind(t *testing.T, req *http.Request) (bindRequest, error) {
+	t.Helper()
+
+	var dst bindRequest
+	var err error
+	r := NewRouter()
+	r.Post("/orgs/{org}", func(ctx *Context) {
+		err = ctx.Bind(&dst)
+	})
+	w := httptest.NewRecorder()
+	r.chi.ServeHTTP(w, req)
+
+	return dst, err
+}
+
+func TestBindSources(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost,
+		"/orgs/42?page=2&limit=50&tag=a&tag=b&id=1&id=2&since=2024-05-01&timeout=1m30s&active=true&level=high&ratio=0.5&Ignored=x&private=y",
+		strings.NewReader(`{"name":"ann","email":"ann@example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Tenant", "acme")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+
+	got, err := bind(t, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	switch {
+	case got.Org != 42, got.Page != 2, got.Limit != 50:
+		t.Errorf("path or embedded query fields: %+v", got)
+	case strings.Join(got.Tags, ",") != "a,b", len(got.IDs) != 2 || got.IDs[1] != 2:
+		t.Errorf("repeated query values: %v %v", got.Tags, got.IDs)
+	case !got.Since.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)), got.Timeout != 90*time.Second:
+		t.Errorf("time values: %v %v", got.Since, got.Timeout)
+	case got.Active == nil || !*got.Active, got.Level != 2, got.Ratio != 0.5:
+		t.Errorf("pointer, TextUnmarshaler or float: %v %v %v", got.Active, got.Level, got.Ratio)
+	case got.Tenant != "acme", got.Session != "s1":
+		t.Errorf("header or cookie: %q %q", got.Tenant, got.Session)
+	case got.Name != "ann", got.Email != "ann@example.com":
+		t.Errorf("body: %q %q", got.Name, got.Email)
+	case got.Ignored != "", got.private != "":
+		t.Errorf("ignored fields were set: %+v", got)
+	}
+}
+
+func TestBindForm(t *testing.T) {
+	form := url.Values{"email": {"bob@example.com"}}
+	req := httptest.NewRequest(http.MethodPost, "/orgs/1?page=3", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	got, err := bind(t, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Email != "bob@example.com" || got.Page != 3 {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestBindMissingValuesKeepFields(t *testing.T) {
+	var dst Paging
+	dst.Limit = 20
+
+	ctx := Si(httptest.NewRequest(http.MethodGet, "/?page=2", nil), httptest.NewRecorder())
+	if err := ctx.Bind(&dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst.Page != 2 || dst.Limit != 20 {
+		t.Fatalf("got %+v", dst)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/orgs/x?page=two&since=yesterday&level=mid&id=1&id=b",
+		strings.NewReader(`{"name": 5}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err := bind(t, req)
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) {
+		t.Fatalf("Bind() = %v, want *BindError", err)
+	}
+
+	got := map[string]string{}
+	for _, fe := range bindErr.Errors {
+		got[fe.Source+":"+fe.Field] = fe.Value
+	}
+	want := map[string]string{
+		"json:name":   "number",
+		"path:org":    "x",
+		"query:page":  "two",
+		"query:id":    "1,b",
+		"query:since": "yesterday",
+		"query:level": "mid",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("errors = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: value %q, want %q", k, got[k], v)
+		}
+	}
+
+	if status := toHTTPError(err).Status; status != http.StatusBadRequest {
+		t.Errorf("rendered as %d, want 400", status)
+	}
+}
+
+func TestBindRequiresStructPointer(t *testing.T) {
+	ctx := Si(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	var n int
+	for _, dst := range []any{nil, Paging{}, &n, (*Paging)(nil)} {
+		if err := ctx.Bind(dst); err == nil {
+			t.Errorf("Bind(%T) succeeded", dst)
+		}
+	}
+}
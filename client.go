@@ -0,0 +1,55 @@
+package si
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/revenkroz/si/middleware"
+	"github.com/revenkroz/si/tracing"
+)
+
+// ClientConfig configures NewClient
+type ClientConfig struct {
+	// Timeout bounds each request, see http.Client.Timeout
+	Timeout time.Duration
+	// Transport defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	// Tracer records a client span for each request. The trace of the
+	// request's context is propagated either way.
+	Tracer *tracing.Tracer
+}
+
+// NewClient returns an HTTP client for calling other services from a
+// handler. Requests made with the handler's context carry its request
+// ID and trace context:
+//
+//	req, _ := http.NewRequestWithContext(ctx.Request.Context(), "GET", url, nil)
+//	resp, err := client.Do(req)
+func NewClient(cfg ClientConfig) *http.Client {
+	return &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &requestIDTransport{
+			base: tracing.NewTransport(cfg.Tracer, cfg.Transport),
+		},
+	}
+}
+
+// requestIDTransport forwards the request ID set by
+// middleware.RequestID in the header it was received in
+type requestIDTransport struct {
+	base http.RoundTripper
+}
+
+func (t *requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if id := middleware.GetRequestID(ctx); id != "" {
+		header := middleware.GetRequestIDHeader(ctx)
+		if req.Header.Get(header) == "" {
+			// A RoundTripper must not modify the caller's request
+			req = req.Clone(ctx)
+			req.Header.Set(header, id)
+		}
+	}
+
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,283 @@
+package si
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// Encoder writes v to w in a particular media type
+type Encoder interface {
+	Encode(w io.Writer, v any) error
+}
+
+// EncoderFunc adapts a function to the Encoder interface
+type EncoderFunc func(w io.Writer, v any) error
+
+// Encode calls f(w, v)
+func (f EncoderFunc) Encode(w io.Writer, v any) error {
+	return f(w, v)
+}
+
+// Decoder reads a value of a particular media type from r into v
+type Decoder interface {
+	Decode(r io.Reader, v any) error
+}
+
+// DecoderFunc adapts a function to the Decoder interface
+type DecoderFunc func(r io.Reader, v any) error
+
+// Decode calls f(r, v)
+func (f DecoderFunc) Decode(r io.Reader, v any) error {
+	return f(r, v)
+}
+
+// Codec both encodes and decodes a media type
+type Codec interface {
+	Encoder
+	Decoder
+}
+
+// mediaEncoder is an Encoder registered for a media type
+type mediaEncoder struct {
+	mediaType string
+	encoder   Encoder
+}
+
+// defaultEncoders are available on every router. The first one is used
+// when the client accepts anything.
+var defaultEncoders = []mediaEncoder{
+	{"application/json", EncoderFunc(func(w io.Writer, v any) error {
+		return json.NewEncoder(w).Encode(v)
+	})},
+	{"application/xml", EncoderFunc(func(w io.Writer, v any) error {
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		enc := xml.NewEncoder(w)
+		if xmlHasRoot(v) {
+			return enc.Encode(v)
+		}
+		// Maps, slices and scalars get a root element so the response is
+		// a well-formed document
+		if err := encodeXMLValue(enc, xml.StartElement{Name: xml.Name{Local: "response"}}, v); err != nil {
+			return err
+		}
+		return enc.Flush()
+	})},
+	{"text/plain", EncoderFunc(func(w io.Writer, v any) error {
+		switch t := v.(type) {
+		case []byte:
+			_, err := w.Write(t)
+			return err
+		default:
+			_, err := fmt.Fprint(w, v)
+			return err
+		}
+	})},
+}
+
+var xmlMarshalerType = reflect.TypeOf((*xml.Marshaler)(nil)).Elem()
+
+// xmlHasRoot reports whether encoding/xml writes v as a single element
+// of its own: a named struct, one with an XMLName field or an
+// xml.Marshaler
+func xmlHasRoot(v any) bool {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return false
+	}
+	if rv.Type().Implements(xmlMarshalerType) {
+		return true
+	}
+
+	t := indirectType(rv.Type())
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	_, ok := t.FieldByName("XMLName")
+
+	return ok || t.Name() != ""
+}
+
+// mediaDecoder is a Decoder registered for a media type
+type mediaDecoder struct {
+	mediaType string
+	decoder   Decoder
+}
+
+// defaultDecoders are available on every router
+var defaultDecoders = []mediaDecoder{
+	{"application/json", DecoderFunc(func(r io.Reader, v any) error {
+		return json.NewDecoder(r).Decode(v)
+	})},
+	{"application/xml", DecoderFunc(func(r io.Reader, v any) error {
+		return xml.NewDecoder(r).Decode(v)
+	})},
+}
+
+// RegisterCodec registers a codec for the given media type, used both
+// by Context.Respond and Context.Decode
+func (r *Router) RegisterCodec(mediaType string, codec Codec) {
+	r.RegisterEncoder(mediaType, codec)
+	r.RegisterDecoder(mediaType, codec)
+}
+
+// RegisterDecoder registers a decoder used by Context.Decode and
+// Context.Bind for request bodies of the given media type on this router
+// and its subrouters. Registering an existing media type replaces its
+// decoder.
+func (r *Router) RegisterDecoder(mediaType string, decoder Decoder) {
+	for i, md := range r.decoders {
+		if md.mediaType == mediaType {
+			r.decoders[i].decoder = decoder
+			return
+		}
+	}
+	r.decoders = append(r.decoders, mediaDecoder{mediaType, decoder})
+}
+
+// decoderFor returns the closest decoder for mediaType in the router
+// chain. Structured syntax suffixes such as "application/vnd.api+json"
+// fall back to the decoder of the base format.
+func (r *Router) decoderFor(mediaType string) Decoder {
+	candidates := []string{mediaType}
+	if i := strings.LastIndexByte(mediaType, '+'); i >= 0 {
+		candidates = append(candidates, "application/"+mediaType[i+1:])
+	}
+	if mediaType == "text/xml" {
+		candidates = append(candidates, "application/xml")
+	}
+
+	for _, candidate := range candidates {
+		for rr := r; rr != nil; rr = rr.parent {
+			for _, md := range rr.decoders {
+				if md.mediaType == candidate {
+					return md.decoder
+				}
+			}
+		}
+		for _, md := range defaultDecoders {
+			if md.mediaType == candidate {
+				return md.decoder
+			}
+		}
+	}
+
+	return nil
+}
+
+// RegisterEncoder registers an encoder used by Context.Respond for the
+// given media type on this router and its subrouters. Registering an
+// existing media type replaces its encoder.
+func (r *Router) RegisterEncoder(mediaType string, encoder Encoder) {
+	for i, me := range r.encoders {
+		if me.mediaType == mediaType {
+			r.encoders[i].encoder = encoder
+			return
+		}
+	}
+	r.encoders = append(r.encoders, mediaEncoder{mediaType, encoder})
+}
+
+// encoderList returns the default encoders overridden and extended by
+// those registered along the router chain, from the root down.
+func (r *Router) encoderList() []mediaEncoder {
+	var chain []*Router
+	for ; r != nil; r = r.parent {
+		chain = append(chain, r)
+	}
+
+	list := append([]mediaEncoder(nil), defaultEncoders...)
+	for i := len(chain) - 1; i >= 0; i-- {
+	next:
+		for _, me := range chain[i].encoders {
+			for j := range list {
+				if list[j].mediaType == me.mediaType {
+					list[j] = me
+					continue next
+				}
+			}
+			list = append(list, me)
+		}
+	}
+
+	return list
+}
+
+// errEmptyBody is reported when Decode gets no body at all
+var errEmptyBody = errors.New("request body is empty")
+
+// Decode decodes the request body into v using the decoder registered
+// for the request Content-Type. It returns a 415 HTTPError when no
+// decoder matches, and a *BindError, rendered as 400, when the body
+// can't be decoded.
+func (ctx *Context) Decode(v any) error {
+	decoder := ctx.router.decoderFor(ctx.ContentType())
+	if decoder == nil {
+		return NewHTTPError(http.StatusUnsupportedMediaType, "")
+	}
+
+	body, err := ctx.GetRawContent()
+	if err != nil {
+		return err
+	}
+
+	if err := decoder.Decode(bytes.NewReader(body), v); err != nil {
+		if len(body) == 0 && errors.Is(err, io.EOF) {
+			err = errEmptyBody
+		}
+		return &BindError{Errors: []*FieldError{bodyFieldError(err)}}
+	}
+
+	return nil
+}
+
+// Respond encodes v in the format that best matches the request's
+// Accept header among the registered encoders. It responds with
+// 406 Not Acceptable when none of them is acceptable.
+func (ctx *Context) Respond(v any, statusCode int) {
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	encoders := ctx.router.encoderList()
+	offers := make([]string, len(encoders))
+	for i, me := range encoders {
+		offers[i] = me.mediaType
+	}
+
+	ctx.Response.Header().Add("Vary", "Accept")
+
+	mediaType := ctx.Negotiate(offers...)
+	if mediaType == "" {
+		ctx.Error(NewHTTPError(http.StatusNotAcceptable, ""))
+		return
+	}
+
+	var encoder Encoder
+	for _, me := range encoders {
+		if me.mediaType == mediaType {
+			encoder = me.encoder
+			break
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := encoder.Encode(buf, v); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if mediaType == "text/plain" {
+		mediaType += "; charset=utf-8"
+	}
+	ctx.Response.Header().Set("Content-Type", mediaType)
+	ctx.SendBytes(buf.Bytes(), statusCode)
+}
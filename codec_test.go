@@ -0,0 +1,121 @@
+package si
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeErrors(t *testing.T) {
+	type item struct {
+		A int `json:"a"`
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"valid", "application/json", `{"a": 1}`, http.StatusNoContent},
+		{"malformed", "application/json", `{"a":`, http.StatusBadRequest},
+		{"type mismatch", "application/json", `{"a":"x"}`, http.StatusBadRequest},
+		{"empty", "application/json", "", http.StatusBadRequest},
+		{"malformed xml", "application/xml", "<item>", http.StatusBadRequest},
+		{"unsupported", "application/msgpack", "\x81", http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRouter()
+			var decodeErr error
+			r.Post("/items", E(func(ctx *Context) error {
+				var v item
+				if decodeErr = ctx.Decode(&v); decodeErr != nil {
+					return decodeErr
+				}
+				ctx.Response.WriteHeader(http.StatusNoContent)
+				return nil
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			r.chi.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%v)", w.Code, tt.want, decodeErr)
+			}
+		})
+	}
+}
+
+func TestDecodeErrorKeepsCause(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":}`))
+	req.Header.Set("Content-Type", "application/json")
+	ctx := Si(req, httptest.NewRecorder())
+	ctx.router = NewRouter()
+
+	var v map[string]int
+	err := ctx.Decode(&v)
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("Decode() = %v, want a wrapped *json.SyntaxError", err)
+	}
+}
+
+func TestRespondXML(t *testing.T) {
+	type user struct {
+		Name string `xml:"name"`
+	}
+	type named struct {
+		XMLName xml.Name `xml:"account"`
+		ID      int      `xml:"id"`
+	}
+
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"slice", []int{1, 2}, "<response><i>1</i><i>2</i></response>"},
+		{"scalar", 42, "<response>42</response>"},
+		{"string", "hi", "<response>hi</response>"},
+		{"map", map[string]any{"b": 2, "a": "x"}, "<response><a>x</a><b>2</b></response>"},
+		{"nil", nil, "<response></response>"},
+		{"nil struct pointer", (*user)(nil), "<response></response>"},
+		{"anonymous struct", struct {
+			N int `xml:"n"`
+		}{1}, "<response><n>1</n></response>"},
+		{"named struct", user{"ann"}, "<user><name>ann</name></user>"},
+		{"struct pointer", &user{"ann"}, "<user><name>ann</name></user>"},
+		{"XMLName", named{ID: 7}, "<account><id>7</id></account>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", "application/xml")
+			w := httptest.NewRecorder()
+			ctx := Si(req, w)
+			ctx.router = NewRouter()
+			ctx.Respond(tt.v, http.StatusOK)
+
+			body := strings.TrimPrefix(w.Body.String(), xml.Header)
+			if body != tt.want {
+				t.Fatalf("body = %q, want %q", body, tt.want)
+			}
+			// The body is a single well-formed document
+			var doc struct {
+				XMLName xml.Name
+			}
+			if err := xml.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
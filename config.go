@@ -0,0 +1,319 @@
+package si
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// Option configures a Server created with New
+type Option func(s *Server)
+
+// Timeouts are the connection timeouts of the server. In options, zero
+// fields keep their current value; a negative value disables the timeout.
+type Timeouts struct {
+	// ReadHeader bounds reading the request headers
+	ReadHeader time.Duration
+	// Read bounds reading the entire request, including the body. It
+	// would cut off slow uploads, so it is disabled by default.
+	Read time.Duration
+	// Write bounds writing the response. It also ends streaming
+	// responses such as SSE, so it is disabled by default.
+	Write time.Duration
+	// Idle bounds waiting for the next request on a keep-alive connection
+	Idle time.Duration
+}
+
+// Config holds the server settings that can be loaded from the
+// environment or a file. Zero fields keep the defaults.
+type Config struct {
+	Addr            string
+	Timeouts        Timeouts
+	MaxHeaderBytes  int
+	ShutdownTimeout time.Duration
+	// H2C enables HTTP/2 over plaintext connections, see WithH2C
+	H2C bool
+}
+
+// DefaultConfig returns the settings used by New: address ":8080", a
+// 5s header timeout against slowloris attacks, a 2m idle timeout, a
+// 1 MiB header limit and a 30s shutdown timeout. The read and write
+// timeouts are disabled, since they would cut off slow uploads and
+// streaming responses.
+func DefaultConfig() Config {
+	return Config{
+		Addr: ":8080",
+		Timeouts: Timeouts{
+			ReadHeader: 5 * time.Second,
+			Idle:       120 * time.Second,
+		},
+		MaxHeaderBytes:  1 << 20,
+		ShutdownTimeout: DefaultShutdownTimeout,
+	}
+}
+
+// WithAddr sets the TCP address to listen on when no listener is added
+// with WithListen, WithListener, WithUnixSocket or WithSystemdSockets
+func WithAddr(addr string) Option {
+	return func(s *Server) {
+		s.server.Addr = addr
+	}
+}
+
+// WithTimeouts sets the connection timeouts
+func WithTimeouts(t Timeouts) Option {
+	return func(s *Server) {
+		setTimeout(&s.server.ReadHeaderTimeout, t.ReadHeader)
+		setTimeout(&s.server.ReadTimeout, t.Read)
+		setTimeout(&s.server.WriteTimeout, t.Write)
+		setTimeout(&s.server.IdleTimeout, t.Idle)
+	}
+}
+
+func setTimeout(dst *time.Duration, d time.Duration) {
+	switch {
+	case d < 0:
+		*dst = 0
+	case d > 0:
+		*dst = d
+	}
+}
+
+// WithMaxHeaderBytes limits the size of request headers
+func WithMaxHeaderBytes(n int) Option {
+	return func(s *Server) {
+		s.server.MaxHeaderBytes = n
+	}
+}
+
+// WithErrorLog sets the logger for connection errors and panics in
+// handlers that aren't recovered. Use slog.NewLogLogger to send them
+// to a slog handler.
+func WithErrorLog(l *log.Logger) Option {
+	return func(s *Server) {
+		s.server.ErrorLog = l
+	}
+}
+
+// WithBaseContext sets the function that returns the base context of
+// requests accepted on a listener
+func WithBaseContext(fn func(ln net.Listener) context.Context) Option {
+	return func(s *Server) {
+		s.baseContext = fn
+	}
+}
+
+// WithMiddleware adds middlewares to the server's router
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(s *Server) {
+		for _, m := range middlewares {
+			s.Router.Use(m)
+		}
+	}
+}
+
+// maxH2CUpgradeBody limits the body of a request upgraded to h2c
+const maxH2CUpgradeBody = 1 << 20
+
+// WithH2C serves HTTP/2 over plaintext connections (h2c) alongside
+// HTTP/1.1 on the same listener, as used behind service meshes and
+// load balancers that terminate TLS. Clients may start with prior
+// knowledge or upgrade an HTTP/1.1 request with "Upgrade: h2c".
+func WithH2C() Option {
+	return func(s *Server) {
+		if s.h2c {
+			return
+		}
+		s.h2c = true
+
+		// net/http serves prior knowledge connections itself; the h2c
+		// handler takes over connections upgraded from HTTP/1.1. It
+		// buffers the body of the upgraded request, so that is limited.
+		upgrade := h2c.NewHandler(s.server.Handler, &http2.Server{})
+		s.server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.EqualFold(r.Header.Get("Upgrade"), "h2c") {
+				r.Body = http.MaxBytesReader(w, r.Body, maxH2CUpgradeBody)
+			}
+			upgrade.ServeHTTP(w, r)
+		})
+
+		protocols := &http.Protocols{}
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		s.server.Protocols = protocols
+	}
+}
+
+// WithHTTP2 tunes HTTP/2, e.g. MaxConcurrentStreams and MaxReadFrameSize.
+// It applies to both TLS and h2c connections.
+func WithHTTP2(cfg http.HTTP2Config) Option {
+	return func(s *Server) {
+		s.server.HTTP2 = &cfg
+	}
+}
+
+// WithShutdownTimeout sets Server.ShutdownTimeout
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.ShutdownTimeout = d
+	}
+}
+
+// WithConfig applies the non-zero settings of c
+func WithConfig(c Config) Option {
+	return func(s *Server) {
+		if c.Addr != "" {
+			WithAddr(c.Addr)(s)
+		}
+		WithTimeouts(c.Timeouts)(s)
+		if c.MaxHeaderBytes != 0 {
+			WithMaxHeaderBytes(c.MaxHeaderBytes)(s)
+		}
+		if c.ShutdownTimeout != 0 {
+			WithShutdownTimeout(c.ShutdownTimeout)(s)
+		}
+		if c.H2C {
+			WithH2C()(s)
+		}
+	}
+}
+
+// ConfigFromEnv reads settings from environment variables named after
+// the fields with prefix, e.g. for prefix "APP":
+//
+//	APP_ADDR, APP_READ_HEADER_TIMEOUT, APP_READ_TIMEOUT, APP_WRITE_TIMEOUT,
+//	APP_IDLE_TIMEOUT, APP_MAX_HEADER_BYTES, APP_SHUTDOWN_TIMEOUT, APP_H2C
+//
+// Durations use time.ParseDuration syntax ("5s", "1m30s"); a negative
+// timeout disables it. Booleans use strconv.ParseBool syntax. Unset
+// variables leave the fields zero.
+func ConfigFromEnv(prefix string) (Config, error) {
+	if prefix != "" && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+
+	var c Config
+	c.Addr = os.Getenv(prefix + "ADDR")
+
+	durations := []struct {
+		name string
+		dst  *time.Duration
+	}{
+		{"READ_HEADER_TIMEOUT", &c.Timeouts.ReadHeader},
+		{"READ_TIMEOUT", &c.Timeouts.Read},
+		{"WRITE_TIMEOUT", &c.Timeouts.Write},
+		{"IDLE_TIMEOUT", &c.Timeouts.Idle},
+		{"SHUTDOWN_TIMEOUT", &c.ShutdownTimeout},
+	}
+	for _, d := range durations {
+		value := os.Getenv(prefix + d.name)
+		if value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("si: %s%s: %w", prefix, d.name, err)
+		}
+		*d.dst = v
+	}
+
+	if value := os.Getenv(prefix + "MAX_HEADER_BYTES"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("si: %sMAX_HEADER_BYTES: %w", prefix, err)
+		}
+		c.MaxHeaderBytes = n
+	}
+
+	if value := os.Getenv(prefix + "H2C"); value != "" {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("si: %sH2C: %w", prefix, err)
+		}
+		c.H2C = b
+	}
+
+	return c, nil
+}
+
+// LoadConfig reads settings from a JSON file:
+//
+//	{
+//	  "addr": ":8080",
+//	  "read_header_timeout": "5s",
+//	  "read_timeout": "30s",
+//	  "write_timeout": "1m",
+//	  "idle_timeout": "2m",
+//	  "max_header_bytes": 1048576,
+//	  "shutdown_timeout": "30s",
+//	  "h2c": false
+//	}
+func LoadConfig(path string) (Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return Config{}, fmt.Errorf("si: load config %s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// UnmarshalJSON reads the file format of LoadConfig
+func (c *Config) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Addr              string `json:"addr"`
+		ReadHeaderTimeout string `json:"read_header_timeout"`
+		ReadTimeout       string `json:"read_timeout"`
+		WriteTimeout      string `json:"write_timeout"`
+		IdleTimeout       string `json:"idle_timeout"`
+		MaxHeaderBytes    int    `json:"max_header_bytes"`
+		ShutdownTimeout   string `json:"shutdown_timeout"`
+		H2C               bool   `json:"h2c"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	c.Addr = raw.Addr
+	c.MaxHeaderBytes = raw.MaxHeaderBytes
+	c.H2C = raw.H2C
+
+	durations := []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"read_header_timeout", raw.ReadHeaderTimeout, &c.Timeouts.ReadHeader},
+		{"read_timeout", raw.ReadTimeout, &c.Timeouts.Read},
+		{"write_timeout", raw.WriteTimeout, &c.Timeouts.Write},
+		{"idle_timeout", raw.IdleTimeout, &c.Timeouts.Idle},
+		{"shutdown_timeout", raw.ShutdownTimeout, &c.ShutdownTimeout},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", d.name, err)
+		}
+		*d.dst = v
+	}
+
+	return nil
+}
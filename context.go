@@ -6,11 +6,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"runtime/debug"
 	"strconv"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/revenkroz/si/middleware"
+	"github.com/revenkroz/si/tracing"
 )
 
 type ContextKey string
@@ -22,6 +26,11 @@ func (c ContextKey) String() string {
 type Context struct {
 	Request  *http.Request
 	Response http.ResponseWriter
+
+	router *Router
+	// describe asks a handler made by Typed for its types instead of
+	// serving a request
+	describe *typedInfo
 }
 
 // SetAttribute sets a key-value pair in the context
@@ -36,6 +45,69 @@ func (ctx *Context) GetAttribute(key ContextKey) interface{} {
 	return ctx.Request.Context().Value(key)
 }
 
+// ShuttingDown returns a channel that is closed when the server starts
+// shutting down. Long-lived handlers should return when it's closed.
+// The channel is nil, and never closes, outside of a Server.
+func (ctx *Context) ShuttingDown() <-chan struct{} {
+	if closing, ok := ctx.Request.Context().Value(serverClosingKey).(context.Context); ok {
+		return closing.Done()
+	}
+
+	return nil
+}
+
+// Span returns the request's trace span started by middleware.Tracing.
+// It is nil without tracing; all Span methods accept a nil receiver.
+func (ctx *Context) Span() *tracing.Span {
+	return tracing.SpanFromContext(ctx.Request.Context())
+}
+
+// Go runs fn in a new goroutine that outlives the handler. fn gets a
+// context that keeps the request's values, such as its span and log
+// fields, but isn't cancelled when the request ends. A panic in fn is
+// recovered and reported like middleware.Recoverer reports panics of
+// the request, instead of crashing the process.
+func (ctx *Context) Go(fn func(c context.Context)) {
+	r := ctx.Request
+	c := context.WithoutCancel(r.Context())
+
+	go func() {
+		defer func() {
+			if rvr := recover(); rvr != nil {
+				middleware.ReportPanic(r, rvr, debug.Stack())
+			}
+		}()
+		fn(c)
+	}()
+}
+
+// RequestID returns the request ID set by middleware.RequestID, or ""
+func (ctx *Context) RequestID() string {
+	return middleware.GetRequestID(ctx.Request.Context())
+}
+
+// Logger returns slog's default logger with the request's method, route
+// pattern and client IP, and the log fields set by middleware (request
+// and trace IDs) and LogWith
+func (ctx *Context) Logger() *slog.Logger {
+	args := []any{"method", ctx.Request.Method}
+	if rctx := chi.RouteContext(ctx.Request.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		args = append(args, "route", rctx.RoutePattern())
+	}
+	args = append(args, "ip", ctx.IP())
+
+	return slog.Default().With(append(args, middleware.LogFields(ctx.Request.Context())...)...)
+}
+
+// LogWith adds slog key-value pairs to the loggers returned by Logger
+// and to the access log written by middleware.Logger
+func (ctx *Context) LogWith(args ...any) {
+	if c := middleware.WithLogFields(ctx.Request.Context()); c != ctx.Request.Context() {
+		ctx.Request = ctx.Request.WithContext(c)
+	}
+	middleware.AddLogFields(ctx.Request.Context(), args...)
+}
+
 // ContentType returns the request Content-Type without parameters
 func (ctx *Context) ContentType() string {
 	ct := ctx.Request.Header.Get("Content-Type")
@@ -92,31 +164,15 @@ func (ctx *Context) IP() string {
 // Header methods
 // -----
 
-// Accepts checks if the client accepts a certain type of content
+// Accepts checks if the client accepts any of the given content types.
+// Offers are media types ("application/json") or extensions ("json").
 func (ctx *Context) Accepts(offers ...string) bool {
-	acceptHeader := ctx.HeaderString("Accept")
-
-	acceptArray := strings.Split(acceptHeader, ",")
-
+	var mediaTypes []string
 	for _, offer := range offers {
-		for _, accept := range acceptArray {
-			accept = strings.TrimSpace(accept)
-
-			if strings.Contains(accept, offer) {
-				return true
-			}
-
-			if strings.Contains(accept, "*/*") {
-				return true
-			}
-
-			if strings.Contains(accept, "*") {
-				return true
-			}
-		}
+		mediaTypes = append(mediaTypes, mediaTypeOffers(offer)...)
 	}
 
-	return false
+	return ctx.Negotiate(mediaTypes...) != ""
 }
 
 // HeaderString gets a header value as a string
@@ -436,6 +492,13 @@ func (ctx *Context) SendStream(stream io.ReadCloser, statusCode int) {
 	_, _ = io.Copy(ctx.Response, stream)
 }
 
+// Error passes err to the router's ErrorHandler and records it on the
+// request's trace span
+func (ctx *Context) Error(err error) {
+	ctx.Span().RecordError(err)
+	ctx.router.errorHandler()(ctx, err)
+}
+
 // -----
 // Response headers methods
 // -----
@@ -0,0 +1,142 @@
+package si
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// HTTPError is an error that carries the HTTP response it should produce.
+// It is recognised by DefaultErrorHandler anywhere in the error chain.
+type HTTPError struct {
+	// Status is the HTTP status code
+	Status int
+	// Code is an optional machine-readable error code
+	Code string
+	// Message is a human-readable message safe to show to clients
+	Message string
+	// Details is optional extra data rendered with the error
+	Details any
+	// Err is the wrapped cause. It is never sent to the client.
+	Err error
+}
+
+// NewHTTPError creates a new HTTPError.
+// If message is empty, the status text is used.
+func NewHTTPError(status int, message string) *HTTPError {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+
+	return &HTTPError{
+		Status:  status,
+		Message: message,
+	}
+}
+
+// Error implements the error interface
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+
+	return e.Message
+}
+
+// Unwrap returns the wrapped cause
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// WithCode sets the machine-readable error code
+func (e *HTTPError) WithCode(code string) *HTTPError {
+	e.Code = code
+	return e
+}
+
+// WithDetails sets extra data rendered with the error
+func (e *HTTPError) WithDetails(details any) *HTTPError {
+	e.Details = details
+	return e
+}
+
+// Wrap sets the underlying cause
+func (e *HTTPError) Wrap(err error) *HTTPError {
+	e.Err = err
+	return e
+}
+
+// DefaultErrorHandler renders errors in the same shape as SendErrorJSON.
+// HTTPError values keep their status and message, a BindError becomes
+// a 400 and ValidationErrors a 422 listing the offending fields; any
+// other error is logged and reported as a 500 without exposing its text.
+func DefaultErrorHandler(ctx *Context, err error) {
+	httpErr := toHTTPError(err)
+
+	if httpErr.Status >= http.StatusInternalServerError {
+		slog.Error("handler error",
+			"method", ctx.Request.Method,
+			"path", ctx.Request.URL.Path,
+			"status", httpErr.Status,
+			"error", err,
+		)
+	}
+
+	body := map[string]interface{}{
+		"code":    httpErr.Status,
+		"message": httpErr.Message,
+	}
+	if httpErr.Code != "" {
+		body["reason"] = httpErr.Code
+	}
+	if httpErr.Details != nil {
+		body["details"] = httpErr.Details
+	}
+
+	ctx.SendJSON(map[string]interface{}{
+		"error": body,
+	}, httpErr.Status)
+}
+
+// toHTTPError finds an HTTPError in the chain of err, converts a known
+// error type, or builds a generic 500 error around it.
+func toHTTPError(err error) *HTTPError {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		if httpErr.Status == 0 {
+			e := *httpErr
+			e.Status = http.StatusInternalServerError
+			return &e
+		}
+		return httpErr
+	}
+
+	var problem *Problem
+	if errors.As(err, &problem) {
+		status := problem.Status
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		message := problem.Detail
+		if message == "" {
+			message = problem.Title
+		}
+		return NewHTTPError(status, message).WithDetails(problem.Extensions).Wrap(err)
+	}
+
+	var bindErr *BindError
+	if errors.As(err, &bindErr) {
+		return NewHTTPError(http.StatusBadRequest, "invalid request parameters").
+			WithDetails(bindErr.Details()).
+			Wrap(err)
+	}
+
+	var validationErrs ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return NewHTTPError(http.StatusUnprocessableEntity, "validation failed").
+			WithDetails(validationErrs.Details()).
+			Wrap(err)
+	}
+
+	return NewHTTPError(http.StatusInternalServerError, "").Wrap(err)
+}
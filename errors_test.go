@@ -0,0 +1,36 @@
+package si
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestToHTTPError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantMessage string
+	}{
+		{"http error", NewHTTPError(http.StatusNotFound, ""), http.StatusNotFound, "Not Found"},
+		{"http error without status", &HTTPError{Message: "x"}, http.StatusInternalServerError, "x"},
+		{"wrapped http error", fmt.Errorf("load: %w", NewHTTPError(http.StatusConflict, "taken")), http.StatusConflict, "taken"},
+		{"problem", &Problem{Status: http.StatusForbidden, Title: "Forbidden", Detail: "no access"}, http.StatusForbidden, "no access"},
+		{"problem without status", &Problem{Title: "Broken"}, http.StatusInternalServerError, "Broken"},
+		{"problem without status or text", &Problem{}, http.StatusInternalServerError, "Internal Server Error"},
+		{"bind error", &BindError{}, http.StatusBadRequest, "invalid request parameters"},
+		{"validation errors", ValidationErrors{}, http.StatusUnprocessableEntity, "validation failed"},
+		{"other", errors.New("boom"), http.StatusInternalServerError, "Internal Server Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toHTTPError(tt.err)
+			if got.Status != tt.wantStatus || got.Message != tt.wantMessage {
+				t.Fatalf("got %d %q, want %d %q", got.Status, got.Message, tt.wantStatus, tt.wantMessage)
+			}
+		})
+	}
+}
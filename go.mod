@@ -1,5 +1,10 @@
 module github.com/revenkroz/si
 
-go 1.22.2
+go 1.24.0
 
-require github.com/go-chi/chi/v5 v5.0.12
+require (
+	github.com/go-chi/chi/v5 v5.0.12
+	golang.org/x/net v0.50.0
+)
+
+require golang.org/x/text v0.34.0 // indirect
@@ -0,0 +1,283 @@
+package si
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Health check statuses
+const (
+	HealthPass = "pass"
+	HealthWarn = "warn"
+	HealthFail = "fail"
+)
+
+// HealthCheck is a named check of a dependency
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+	// Timeout bounds the check. Defaults to HealthConfig.Timeout.
+	Timeout time.Duration
+	// Optional checks report "warn" instead of failing the endpoint
+	Optional bool
+	// Liveness runs the check on the liveness endpoint too. Only use it
+	// for checks whose failure means the process must be restarted.
+	Liveness bool
+}
+
+// HealthConfig configures the health endpoints
+type HealthConfig struct {
+	// LivenessPath defaults to "/livez"
+	LivenessPath string
+	// ReadinessPath defaults to "/readyz"
+	ReadinessPath string
+	// Timeout is the default check timeout. Defaults to 5s.
+	Timeout time.Duration
+	// CacheTTL is how long results are reused. Defaults to 1s.
+	CacheTTL time.Duration
+	// ShutdownDelay keeps serving with failing readiness for this long
+	// before draining, so load balancers stop sending traffic first.
+	ShutdownDelay time.Duration
+	Checks        []HealthCheck
+}
+
+// HealthReport is the JSON document served by the health endpoints
+type HealthReport struct {
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks,omitempty"`
+}
+
+// HealthCheckResult is the result of a single check
+type HealthCheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// Health serves the liveness and readiness endpoints of a server
+type Health struct {
+	cfg      HealthConfig
+	draining atomic.Bool
+
+	mu     sync.Mutex
+	checks []HealthCheck
+
+	// Liveness and readiness are cached and run separately, so a slow
+	// readiness check never delays the liveness probe
+	liveness  probe
+	readiness probe
+}
+
+// probe caches the report of one endpoint and lets concurrent requests
+// share a single run of its checks
+type probe struct {
+	mu      sync.Mutex
+	report  HealthReport
+	at      time.Time
+	gen     uint64
+	running chan struct{}
+}
+
+// Health registers liveness and readiness endpoints on the server.
+// Readiness fails as soon as the server starts shutting down.
+func (s *Server) Health(cfg HealthConfig) *Health {
+	if cfg.LivenessPath == "" {
+		cfg.LivenessPath = "/livez"
+	}
+	if cfg.ReadinessPath == "" {
+		cfg.ReadinessPath = "/readyz"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = time.Second
+	}
+
+	h := &Health{
+		cfg:    cfg,
+		checks: cfg.Checks,
+	}
+
+	s.beforeShutdown = append(s.beforeShutdown, func() {
+		h.draining.Store(true)
+		if cfg.ShutdownDelay > 0 {
+			time.Sleep(cfg.ShutdownDelay)
+		}
+	})
+
+	s.Router.Get(cfg.LivenessPath, func(ctx *Context) {
+		h.respond(ctx, h.report(true))
+	}).Hidden()
+	s.Router.Get(cfg.ReadinessPath, func(ctx *Context) {
+		if h.draining.Load() {
+			h.respond(ctx, HealthReport{Status: HealthFail})
+			return
+		}
+		h.respond(ctx, h.report(false))
+	}).Hidden()
+
+	return h
+}
+
+// AddCheck registers a check
+func (h *Health) AddCheck(check HealthCheck) *Health {
+	h.mu.Lock()
+	h.checks = append(h.checks, check)
+	h.mu.Unlock()
+
+	for _, p := range []*probe{&h.liveness, &h.readiness} {
+		p.mu.Lock()
+		p.at = time.Time{}
+		p.gen++
+		p.mu.Unlock()
+	}
+
+	return h
+}
+
+func (h *Health) respond(ctx *Context, report HealthReport) {
+	status := http.StatusOK
+	if report.Status == HealthFail {
+		status = http.StatusServiceUnavailable
+	}
+
+	ctx.Response.Header().Set("Cache-Control", "no-store")
+	ctx.SendJSON(report, status)
+}
+
+// report returns the cached report of the probe, or runs its checks.
+// Concurrent callers wait for a single run; no lock is held while the
+// checks run.
+func (h *Health) report(liveness bool) HealthReport {
+	p := &h.readiness
+	if liveness {
+		p = &h.liveness
+	}
+
+	p.mu.Lock()
+	if !p.at.IsZero() && time.Since(p.at) < h.cfg.CacheTTL {
+		report := p.report
+		p.mu.Unlock()
+		return report
+	}
+	if running := p.running; running != nil {
+		p.mu.Unlock()
+		<-running
+		p.mu.Lock()
+		report := p.report
+		p.mu.Unlock()
+		return report
+	}
+	running := make(chan struct{})
+	p.running = running
+	gen := p.gen
+	p.mu.Unlock()
+
+	report := h.runChecks(liveness)
+
+	p.mu.Lock()
+	p.report = report
+	// A check added during the run invalidates the result
+	if p.gen == gen {
+		p.at = time.Now()
+	}
+	p.running = nil
+	close(running)
+	p.mu.Unlock()
+
+	return report
+}
+
+// runChecks runs the checks of a probe concurrently
+func (h *Health) runChecks(liveness bool) HealthReport {
+	h.mu.Lock()
+	var checks []HealthCheck
+	for _, c := range h.checks {
+		if !liveness || c.Liveness {
+			checks = append(checks, c)
+		}
+	}
+	h.mu.Unlock()
+
+	report := HealthReport{Status: HealthPass}
+	if len(checks) > 0 {
+		report.Checks = make(map[string]HealthCheckResult, len(checks))
+	}
+
+	results := make([]HealthCheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = h.run(c)
+		}()
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		result := results[i]
+		report.Checks[c.Name] = result
+		switch {
+		case result.Status == HealthPass:
+		case c.Optional:
+			if report.Status == HealthPass {
+				report.Status = HealthWarn
+			}
+		default:
+			report.Status = HealthFail
+		}
+	}
+
+	return report
+}
+
+// run runs a single check with its timeout. A check that doesn't return
+// in time, or panics, fails.
+func (h *Health) run(c HealthCheck) (result HealthCheckResult) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = h.cfg.Timeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- fmt.Errorf("panic: %v", rec)
+			}
+		}()
+		done <- c.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result = HealthCheckResult{
+		Status:   HealthPass,
+		Duration: time.Since(start).Round(time.Microsecond).String(),
+		Optional: c.Optional,
+	}
+	if err != nil {
+		result.Status = HealthFail
+		if c.Optional {
+			result.Status = HealthWarn
+		}
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,116 @@
+package si
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func serveHealth(t *testing.T, s *Server, path string) (int, HealthReport) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	s.Router.chi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var report HealthReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Errorf("%s: decoding report: %v", path, err)
+	}
+
+	return w.Code, report
+}
+
+func TestHealthSlowReadinessDoesNotBlockLiveness(t *testing.T) {
+	s := New()
+	release := make(chan struct{})
+	s.Health(HealthConfig{Checks: []HealthCheck{
+		{Name: "db", Check: func(ctx context.Context) error {
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return nil
+		}},
+		{Name: "self", Liveness: true, Check: func(context.Context) error { return nil }},
+	}})
+	defer close(release)
+
+	go serveHealth(t, s, "/readyz")
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan int, 1)
+	go func() {
+		code, _ := serveHealth(t, s, "/livez")
+		done <- code
+	}()
+
+	select {
+	case code := <-done:
+		if code != http.StatusOK {
+			t.Fatalf("/livez = %d, want 200", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("/livez waited for the readiness checks")
+	}
+}
+
+func TestHealthConcurrentRequestsShareOneRun(t *testing.T) {
+	s := New()
+	var runs atomic.Int32
+	s.Health(HealthConfig{Checks: []HealthCheck{
+		{Name: "db", Check: func(context.Context) error {
+			runs.Add(1)
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		}},
+	}})
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serveHealth(t, s, "/readyz")
+		}()
+	}
+	wg.Wait()
+
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("checks ran %d times, want 1", n)
+	}
+}
+
+func TestHealthStatus(t *testing.T) {
+	fail := func(context.Context) error { return context.DeadlineExceeded }
+	pass := func(context.Context) error { return nil }
+
+	tests := []struct {
+		name       string
+		checks     []HealthCheck
+		wantCode   int
+		wantStatus string
+	}{
+		{"no checks", nil, http.StatusOK, HealthPass},
+		{"passing", []HealthCheck{{Name: "a", Check: pass}}, http.StatusOK, HealthPass},
+		{"optional failing", []HealthCheck{{Name: "a", Check: pass}, {Name: "b", Check: fail, Optional: true}}, http.StatusOK, HealthWarn},
+		{"required failing", []HealthCheck{{Name: "a", Check: fail}, {Name: "b", Check: fail, Optional: true}}, http.StatusServiceUnavailable, HealthFail},
+		{"panicking", []HealthCheck{{Name: "a", Check: func(context.Context) error { panic("boom") }}}, http.StatusServiceUnavailable, HealthFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.Health(HealthConfig{Checks: tt.checks})
+
+			code, report := serveHealth(t, s, "/readyz")
+			if code != tt.wantCode || report.Status != tt.wantStatus {
+				t.Fatalf("got %d %q, want %d %q", code, report.Status, tt.wantCode, tt.wantStatus)
+			}
+		})
+	}
+}
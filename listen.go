@@ -0,0 +1,241 @@
+package si
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listenFunc opens listeners when the server starts
+type listenFunc func() ([]net.Listener, error)
+
+// UnixSocket describes a Unix domain socket to listen on
+type UnixSocket struct {
+	Path string
+	// Mode sets the permissions of the socket file, e.g. 0o660.
+	// Zero keeps the permissions given by the umask.
+	Mode os.FileMode
+	// Owner and Group change the ownership of the socket file. They
+	// are names or numeric IDs; empty values leave it unchanged.
+	Owner string
+	Group string
+}
+
+// WithListen adds a listener on the network address, e.g.
+// WithListen("tcp", ":8080") or WithListen("tcp6", "[::1]:8080").
+// The server's Addr is only used when no listener is added.
+func WithListen(network, address string) Option {
+	return func(s *Server) {
+		s.listenFuncs = append(s.listenFuncs, func() ([]net.Listener, error) {
+			ln, err := net.Listen(network, address)
+			if err != nil {
+				return nil, err
+			}
+			return []net.Listener{ln}, nil
+		})
+	}
+}
+
+// WithListener serves on an already open listener. The server takes
+// ownership of it and closes it on shutdown.
+func WithListener(ln net.Listener) Option {
+	return func(s *Server) {
+		s.listenFuncs = append(s.listenFuncs, func() ([]net.Listener, error) {
+			return []net.Listener{ln}, nil
+		})
+	}
+}
+
+// WithUnixSocket adds a listener on a Unix domain socket. A stale
+// socket file left by a previous process is removed; the file is
+// removed again on shutdown.
+func WithUnixSocket(sock UnixSocket) Option {
+	return func(s *Server) {
+		s.listenFuncs = append(s.listenFuncs, func() ([]net.Listener, error) {
+			ln, err := listenUnix(sock)
+			if err != nil {
+				return nil, fmt.Errorf("si: unix socket %s: %w", sock.Path, err)
+			}
+			return []net.Listener{ln}, nil
+		})
+	}
+}
+
+// WithSystemdSockets serves on the sockets passed by systemd socket
+// activation (LISTEN_FDS). When the process wasn't socket-activated, no
+// listener is added, so the same binary also runs standalone.
+func WithSystemdSockets() Option {
+	return func(s *Server) {
+		s.listenFuncs = append(s.listenFuncs, systemdListeners)
+	}
+}
+
+// openListeners opens the configured listeners, falling back to Addr
+func (s *Server) openListeners() ([]net.Listener, error) {
+	// After a graceful restart the parent's listeners replace all others
+	lns, err := inheritedListeners()
+	if err != nil || lns != nil {
+		return lns, err
+	}
+
+	for _, open := range s.listenFuncs {
+		opened, err := open()
+		if err != nil {
+			closeListeners(lns)
+			return nil, err
+		}
+		lns = append(lns, opened...)
+	}
+
+	if len(lns) == 0 {
+		ln, err := net.Listen("tcp", s.server.Addr)
+		if err != nil {
+			return nil, err
+		}
+		lns = append(lns, ln)
+	}
+
+	return lns, nil
+}
+
+func closeListeners(lns []net.Listener) {
+	for _, ln := range lns {
+		_ = ln.Close()
+	}
+}
+
+// listenerName describes a listener for the startup message
+func listenerName(ln net.Listener) string {
+	addr := ln.Addr()
+	if addr.Network() == "unix" {
+		return "unix:" + addr.String()
+	}
+
+	return addr.String()
+}
+
+func listenUnix(sock UnixSocket) (net.Listener, error) {
+	if err := removeStaleSocket(sock.Path); err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", sock.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if sock.Mode != 0 {
+		if err := os.Chmod(sock.Path, sock.Mode); err != nil {
+			_ = ln.Close()
+			return nil, err
+		}
+	}
+
+	if sock.Owner != "" || sock.Group != "" {
+		uid, gid, err := lookupOwner(sock.Owner, sock.Group)
+		if err == nil {
+			err = os.Chown(sock.Path, uid, gid)
+		}
+		if err != nil {
+			_ = ln.Close()
+			return nil, err
+		}
+	}
+
+	return ln, nil
+}
+
+// removeStaleSocket removes a socket file nobody is listening on
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return errors.New("file exists and is not a socket")
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		_ = conn.Close()
+		return errors.New("socket is in use")
+	}
+
+	return os.Remove(path)
+}
+
+// lookupOwner resolves user and group names or IDs. Empty values
+// resolve to -1, which os.Chown leaves unchanged.
+func lookupOwner(owner, group string) (uid, gid int, err error) {
+	uid, gid = -1, -1
+
+	if owner != "" {
+		if uid, err = strconv.Atoi(owner); err != nil {
+			u, err := user.Lookup(owner)
+			if err != nil {
+				return 0, 0, err
+			}
+			uid, _ = strconv.Atoi(u.Uid)
+		}
+	}
+
+	if group != "" {
+		if gid, err = strconv.Atoi(group); err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return 0, 0, err
+			}
+			gid, _ = strconv.Atoi(g.Gid)
+		}
+	}
+
+	return uid, gid, nil
+}
+
+// systemdListeners returns the sockets passed by systemd. See
+// sd_listen_fds(3): descriptors start at 3 and the variables are only
+// meant for the process named by LISTEN_PID.
+func systemdListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	// Child processes must not pick the sockets up again
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+
+	var lns []net.Listener
+	for i := 0; i < n; i++ {
+		name := "LISTEN_FD_" + strconv.Itoa(3+i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		f := os.NewFile(uintptr(3+i), name)
+		// FileListener duplicates the descriptor, so the original is closed
+		ln, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			closeListeners(lns)
+			return nil, fmt.Errorf("si: systemd socket %s: %w", name, err)
+		}
+		lns = append(lns, ln)
+	}
+
+	return lns, nil
+}
@@ -0,0 +1,224 @@
+// Package metrics is a dependency-free metrics registry with counters,
+// gauges and histograms, exposed in the Prometheus text format.
+package metrics
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefBuckets are the default histogram buckets, in seconds, suited to
+// request latencies
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// atomicFloat is a float64 updated atomically
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) Load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+func (f *atomicFloat) Store(v float64) {
+	f.bits.Store(math.Float64bits(v))
+}
+
+func (f *atomicFloat) Add(v float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// Counter is a value that only goes up
+type Counter struct {
+	v atomicFloat
+}
+
+// Inc adds 1
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+// Add adds v, which must not be negative
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.v.Add(v)
+}
+
+// Value returns the current value
+func (c *Counter) Value() float64 {
+	return c.v.Load()
+}
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	v atomicFloat
+}
+
+// Set sets the value
+func (g *Gauge) Set(v float64) {
+	g.v.Store(v)
+}
+
+// Inc adds 1
+func (g *Gauge) Inc() {
+	g.v.Add(1)
+}
+
+// Dec subtracts 1
+func (g *Gauge) Dec() {
+	g.v.Add(-1)
+}
+
+// Add adds v
+func (g *Gauge) Add(v float64) {
+	g.v.Add(v)
+}
+
+// Value returns the current value
+func (g *Gauge) Value() float64 {
+	return g.v.Load()
+}
+
+// Histogram counts observations in buckets
+type Histogram struct {
+	upper  []float64
+	counts []atomic.Uint64
+	count  atomic.Uint64
+	sum    atomicFloat
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		upper:  buckets,
+		counts: make([]atomic.Uint64, len(buckets)),
+	}
+}
+
+// Observe records a value
+func (h *Histogram) Observe(v float64) {
+	if i := sort.SearchFloat64s(h.upper, v); i < len(h.upper) {
+		h.counts[i].Add(1)
+	}
+	h.sum.Add(v)
+	h.count.Add(1)
+}
+
+// Count returns the number of observations
+func (h *Histogram) Count() uint64 {
+	return h.count.Load()
+}
+
+// Sum returns the sum of observations
+func (h *Histogram) Sum() float64 {
+	return h.sum.Load()
+}
+
+// vec holds the series of a labelled metric
+type vec[T any] struct {
+	labels []string
+	create func() T
+
+	mu     sync.RWMutex
+	series map[string]*series[T]
+}
+
+type series[T any] struct {
+	values []string
+	metric T
+}
+
+func newVec[T any](labels []string, create func() T) *vec[T] {
+	return &vec[T]{
+		labels: labels,
+		create: create,
+		series: map[string]*series[T]{},
+	}
+}
+
+// with returns the series for the label values, creating it
+func (v *vec[T]) with(values []string) T {
+	if len(values) != len(v.labels) {
+		panic("metrics: expected " + strconv.Itoa(len(v.labels)) + " label values, got " + strconv.Itoa(len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	v.mu.RLock()
+	s, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return s.metric
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok := v.series[key]; ok {
+		return s.metric
+	}
+	s = &series[T]{values: append([]string(nil), values...), metric: v.create()}
+	v.series[key] = s
+
+	return s.metric
+}
+
+// snapshot returns the series sorted by label values
+func (v *vec[T]) snapshot() []*series[T] {
+	v.mu.RLock()
+	list := make([]*series[T], 0, len(v.series))
+	for _, s := range v.series {
+		list = append(list, s)
+	}
+	v.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i].values, list[j].values
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+
+	return list
+}
+
+// CounterVec is a counter with labels
+type CounterVec struct {
+	*vec[*Counter]
+}
+
+// With returns the counter for the label values, in label order
+func (v *CounterVec) With(values ...string) *Counter {
+	return v.with(values)
+}
+
+// GaugeVec is a gauge with labels
+type GaugeVec struct {
+	*vec[*Gauge]
+}
+
+// With returns the gauge for the label values, in label order
+func (v *GaugeVec) With(values ...string) *Gauge {
+	return v.with(values)
+}
+
+// HistogramVec is a histogram with labels
+type HistogramVec struct {
+	*vec[*Histogram]
+}
+
+// With returns the histogram for the label values, in label order
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return v.with(values)
+}
@@ -0,0 +1,251 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	nameRegexp  = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Default is the registry used by the middleware when none is given
+var Default = NewRegistry()
+
+// Registry holds metric families and writes them in the Prometheus
+// text format
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// family is a registered metric with all its series
+type family struct {
+	name    string
+	help    string
+	typ     string
+	labels  []string
+	buckets []float64
+	metric  any
+	write   func(buf *bytes.Buffer, f *family)
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]*family{}}
+}
+
+// register adds a family, or returns the existing one with the same
+// definition. A conflicting definition panics.
+func (r *Registry) register(name, help, typ string, labels []string, buckets []float64, create func() any, write func(*bytes.Buffer, *family)) *family {
+	if !nameRegexp.MatchString(name) {
+		panic(fmt.Sprintf("metrics: invalid metric name %q", name))
+	}
+	for _, l := range labels {
+		if !labelRegexp.MatchString(l) || strings.HasPrefix(l, "__") || (typ == "histogram" && l == "le") {
+			panic(fmt.Sprintf("metrics: invalid label name %q for %s", l, name))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if f, ok := r.families[name]; ok {
+		if f.typ != typ || !slices.Equal(f.labels, labels) || !slices.Equal(f.buckets, buckets) {
+			panic(fmt.Sprintf("metrics: %s is already registered with a different definition", name))
+		}
+		return f
+	}
+
+	f := &family{
+		name:    name,
+		help:    help,
+		typ:     typ,
+		labels:  labels,
+		buckets: buckets,
+		metric:  create(),
+		write:   write,
+	}
+	r.families[name] = f
+
+	return f
+}
+
+// NewCounter registers a counter. Registering the same counter again
+// returns the existing one.
+func (r *Registry) NewCounter(name, help string) *Counter {
+	return r.NewCounterVec(name, help).With()
+}
+
+// NewCounterVec registers a counter with labels
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	f := r.register(name, help, "counter", labels, nil, func() any {
+		return &CounterVec{newVec(labels, func() *Counter { return &Counter{} })}
+	}, func(buf *bytes.Buffer, f *family) {
+		for _, s := range f.metric.(*CounterVec).snapshot() {
+			writeSample(buf, f.name, f.labels, s.values, "", "", s.metric.Value())
+		}
+	})
+
+	return f.metric.(*CounterVec)
+}
+
+// NewGauge registers a gauge
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	return r.NewGaugeVec(name, help).With()
+}
+
+// NewGaugeVec registers a gauge with labels
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	f := r.register(name, help, "gauge", labels, nil, func() any {
+		return &GaugeVec{newVec(labels, func() *Gauge { return &Gauge{} })}
+	}, func(buf *bytes.Buffer, f *family) {
+		for _, s := range f.metric.(*GaugeVec).snapshot() {
+			writeSample(buf, f.name, f.labels, s.values, "", "", s.metric.Value())
+		}
+	})
+
+	return f.metric.(*GaugeVec)
+}
+
+// NewGaugeFunc registers a gauge whose value is read from fn on every
+// scrape, e.g. the number of goroutines
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, help, "gauge", nil, nil, func() any {
+		return fn
+	}, func(buf *bytes.Buffer, f *family) {
+		writeSample(buf, f.name, nil, nil, "", "", f.metric.(func() float64)())
+	})
+}
+
+// NewHistogram registers a histogram. Nil buckets use DefBuckets.
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	return r.NewHistogramVec(name, help, buckets).With()
+}
+
+// NewHistogramVec registers a histogram with labels. Nil buckets use
+// DefBuckets.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	buckets = slices.Clone(buckets)
+	sort.Float64s(buckets)
+	if n := len(buckets); n > 0 && math.IsInf(buckets[n-1], 1) {
+		buckets = buckets[:n-1]
+	}
+
+	f := r.register(name, help, "histogram", labels, buckets, func() any {
+		return &HistogramVec{newVec(labels, func() *Histogram { return newHistogram(buckets) })}
+	}, func(buf *bytes.Buffer, f *family) {
+		for _, s := range f.metric.(*HistogramVec).snapshot() {
+			h := s.metric
+			var cumulative uint64
+			for i, upper := range h.upper {
+				cumulative += h.counts[i].Load()
+				writeSample(buf, f.name+"_bucket", f.labels, s.values, "le", formatFloat(upper), float64(cumulative))
+			}
+			count := h.count.Load()
+			writeSample(buf, f.name+"_bucket", f.labels, s.values, "le", "+Inf", float64(count))
+			writeSample(buf, f.name+"_sum", f.labels, s.values, "", "", h.sum.Load())
+			writeSample(buf, f.name+"_count", f.labels, s.values, "", "", float64(count))
+		}
+	})
+
+	return f.metric.(*HistogramVec)
+}
+
+// WriteTo writes all metrics in the Prometheus text format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].name < families[j].name
+	})
+
+	buf := &bytes.Buffer{}
+	for _, f := range families {
+		if f.help != "" {
+			buf.WriteString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n")
+		}
+		buf.WriteString("# TYPE " + f.name + " " + f.typ + "\n")
+		f.write(buf, f)
+	}
+
+	return buf.WriteTo(w)
+}
+
+// Handler serves the metrics for Prometheus to scrape
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_, _ = r.WriteTo(w)
+	})
+}
+
+func writeSample(buf *bytes.Buffer, name string, labels, values []string, extraLabel, extraValue string, v float64) {
+	buf.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		buf.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(l + `="` + escapeLabel(values[i]) + `"`)
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(extraLabel + `="` + extraValue + `"`)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(formatFloat(v))
+	buf.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func exposition(t *testing.T, r *Registry) string {
+	t.Helper()
+
+	var sb strings.Builder
+	if _, err := r.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+
+	return sb.String()
+}
+
+func TestWriteTo(t *testing.T) {
+	r := NewRegistry()
+
+	requests := r.NewCounterVec("http_requests_total", "Requests by method and path.", "method", "path")
+	requests.With("POST", "/users").Add(2)
+	requests.With("GET", `/a"b\c`+"\nd").Inc()
+
+	inflight := r.NewGauge("http_in_flight", "")
+	inflight.Inc()
+	inflight.Inc()
+	inflight.Dec()
+
+	r.NewGaugeFunc("goroutines", "Goroutines\nthat exist, see C:\\docs.", func() float64 { return 7 })
+
+	latency := r.NewHistogramVec("latency_seconds", "Latency.", []float64{1, 0.1, 0.5}, "code")
+	for _, v := range []float64{0.05, 0.1, 0.3, 2} {
+		latency.With("200").Observe(v)
+	}
+
+	// Families are sorted by name, series by label values
+	want := `# HELP goroutines Goroutines\nthat exist, see C:\\docs.
+# TYPE goroutines gauge
+goroutines 7
+# TYPE http_in_flight gauge
+http_in_flight 1
+# HELP http_requests_total Requests by method and path.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",path="/a\"b\\c\nd"} 1
+http_requests_total{method="POST",path="/users"} 2
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{code="200",le="0.1"} 2
+latency_seconds_bucket{code="200",le="0.5"} 3
+latency_seconds_bucket{code="200",le="1"} 3
+latency_seconds_bucket{code="200",le="+Inf"} 4
+latency_seconds_sum{code="200"} 2.45
+latency_seconds_count{code="200"} 4
+`
+	if got := exposition(t, r); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHistogramWithoutLabels(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("size_bytes", "", []float64{10, 100, math.Inf(1)})
+	h.Observe(10)
+	h.Observe(1000)
+
+	want := `# TYPE size_bytes histogram
+size_bytes_bucket{le="10"} 1
+size_bytes_bucket{le="100"} 1
+size_bytes_bucket{le="+Inf"} 2
+size_bytes_sum 1010
+size_bytes_count 2
+`
+	if got := exposition(t, r); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if h.Count() != 2 || h.Sum() != 1010 {
+		t.Fatalf("Count() = %d, Sum() = %v", h.Count(), h.Sum())
+	}
+}
+
+func TestRegisterAgain(t *testing.T) {
+	r := NewRegistry()
+	a := r.NewCounterVec("jobs_total", "Jobs.", "queue")
+	b := r.NewCounterVec("jobs_total", "Jobs.", "queue")
+	a.With("mail").Inc()
+	b.With("mail").Inc()
+
+	if v := a.With("mail").Value(); v != 2 {
+		t.Fatalf("value = %v, want the same counter for both registrations", v)
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(r *Registry)
+	}{
+		{"metric name", func(r *Registry) { r.NewCounter("http-requests", "") }},
+		{"label name", func(r *Registry) { r.NewCounterVec("requests_total", "", "status code") }},
+		{"reserved label", func(r *Registry) { r.NewGaugeVec("temperature", "", "__name") }},
+		{"le on a histogram", func(r *Registry) { r.NewHistogramVec("latency", "", nil, "le") }},
+		{"different type", func(r *Registry) {
+			r.NewCounter("things", "")
+			r.NewGauge("things", "")
+		}},
+		{"different labels", func(r *Registry) {
+			r.NewCounterVec("things", "", "a")
+			r.NewCounterVec("things", "", "b")
+		}},
+		{"label value count", func(r *Registry) { r.NewCounterVec("things", "", "a").With() }},
+		{"negative counter", func(r *Registry) { r.NewCounter("things", "").Add(-1) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("did not panic")
+				}
+			}()
+			tt.fn(NewRegistry())
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("up", "").Inc()
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != ContentType {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	if body := w.Body.String(); body != "# TYPE up counter\nup 1\n" {
+		t.Fatalf("body = %q", body)
+	}
+}
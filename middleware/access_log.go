@@ -0,0 +1,375 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AccessLogFormat selects how AccessLog writes records
+type AccessLogFormat int
+
+const (
+	// FormatSlog logs records through AccessLogConfig.Logger
+	FormatSlog AccessLogFormat = iota
+	// FormatJSON writes one JSON object per line to AccessLogConfig.Output
+	FormatJSON
+	// FormatCombined writes Apache's combined log format to
+	// AccessLogConfig.Output
+	FormatCombined
+)
+
+// AccessLogField is a field of slog and JSON access log records
+type AccessLogField string
+
+const (
+	FieldMethod    AccessLogField = "method"
+	FieldPath      AccessLogField = "path"
+	FieldQuery     AccessLogField = "query"
+	FieldRoute     AccessLogField = "route"
+	FieldStatus    AccessLogField = "status"
+	FieldDuration  AccessLogField = "duration"
+	FieldBytes     AccessLogField = "bytes"
+	FieldUserAgent AccessLogField = "user_agent"
+	FieldReferer   AccessLogField = "referer"
+	FieldRequestID AccessLogField = "request_id"
+	FieldRemoteIP  AccessLogField = "remote_ip"
+	FieldProto     AccessLogField = "proto"
+	FieldHost      AccessLogField = "host"
+)
+
+// DefaultAccessLogFields are the fields logged when none are selected
+var DefaultAccessLogFields = []AccessLogField{
+	FieldMethod, FieldPath, FieldRoute, FieldStatus, FieldDuration,
+}
+
+// AccessLogConfig configures AccessLog
+type AccessLogConfig struct {
+	Format AccessLogFormat
+	// Logger is used by FormatSlog. Defaults to slog.Default().
+	Logger *slog.Logger
+	// Output receives FormatJSON and FormatCombined lines. Defaults to
+	// os.Stdout.
+	Output io.Writer
+	// Fields selects the fields of FormatSlog and FormatJSON records.
+	// Defaults to DefaultAccessLogFields. The request's log fields (see
+	// AddLogFields) are always added.
+	Fields []AccessLogField
+	// Level returns the level of a record. Defaults to LevelByStatus.
+	Level func(status int) slog.Level
+	// SkipPaths are request paths that are never logged, e.g. "/healthz"
+	SkipPaths []string
+	// Skip reports whether a request should not be logged
+	Skip func(r *http.Request, status int) bool
+	// SampleRate is the fraction of requests logged, between 0 and 1.
+	// Zero logs every request.
+	SampleRate float64
+	// SampleRoutes overrides SampleRate for route patterns, e.g.
+	// {"/events/{id}": 0.01}. A rate of zero logs none of the route's
+	// requests.
+	SampleRoutes map[string]float64
+	// SampleErrors applies sampling to 5xx responses too; by default
+	// they are always logged
+	SampleErrors bool
+}
+
+// LevelByStatus logs 5xx responses as errors, 4xx as warnings and the
+// rest as info
+func LevelByStatus(status int) slog.Level {
+	switch {
+	case status >= 500:
+		return slog.LevelError
+	case status >= 400:
+		return slog.LevelWarn
+	}
+
+	return slog.LevelInfo
+}
+
+var defaultAccessLog = AccessLog(AccessLogConfig{})
+
+// Logger logs each request's method, path, route pattern, status and
+// duration via slog, along with the request's log fields, such as the
+// request ID and fields added by handlers with AddLogFields. Use
+// AccessLog to configure it.
+func Logger(next http.Handler) http.Handler {
+	return defaultAccessLog(next)
+}
+
+// AccessLog logs requests as configured by cfg
+func AccessLog(cfg AccessLogConfig) func(http.Handler) http.Handler {
+	if cfg.Output == nil {
+		cfg.Output = os.Stdout
+	}
+	if cfg.Fields == nil {
+		cfg.Fields = DefaultAccessLogFields
+	}
+	if cfg.Level == nil {
+		cfg.Level = LevelByStatus
+	}
+
+	l := &accessLogger{cfg: cfg, out: &lockedWriter{w: cfg.Output}}
+	if cfg.Format == FormatJSON {
+		l.json = slog.New(slog.NewJSONHandler(l.out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			r = r.WithContext(WithLogFields(r.Context()))
+			next.ServeHTTP(rec, r)
+
+			entry := accessEntry{
+				r:        r,
+				w:        rec,
+				start:    start,
+				duration: time.Since(start),
+			}
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				entry.route = rctx.RoutePattern()
+			}
+			if l.skip(entry) {
+				return
+			}
+			l.log(entry)
+		})
+	}
+}
+
+type accessLogger struct {
+	cfg  AccessLogConfig
+	out  *lockedWriter
+	json *slog.Logger
+}
+
+type accessEntry struct {
+	r        *http.Request
+	w        *responseRecorder
+	route    string
+	start    time.Time
+	duration time.Duration
+}
+
+// skip applies the filters and sampling
+func (l *accessLogger) skip(e accessEntry) bool {
+	if slices.Contains(l.cfg.SkipPaths, e.r.URL.Path) {
+		return true
+	}
+	if l.cfg.Skip != nil && l.cfg.Skip(e.r, e.w.status) {
+		return true
+	}
+	if e.w.status >= 500 && !l.cfg.SampleErrors {
+		return false
+	}
+
+	rate := 1.0
+	if l.cfg.SampleRate > 0 {
+		rate = l.cfg.SampleRate
+	}
+	if r, ok := l.cfg.SampleRoutes[e.route]; ok && e.route != "" {
+		rate = r
+	}
+	switch {
+	case rate >= 1:
+		return false
+	case rate <= 0:
+		return true
+	}
+
+	return rand.Float64() >= rate
+}
+
+func (l *accessLogger) log(e accessEntry) {
+	fields := LogFields(e.r.Context())
+
+	switch l.cfg.Format {
+	case FormatCombined:
+		_, _ = io.WriteString(l.out, combinedLine(e))
+	case FormatJSON:
+		l.json.Log(e.r.Context(), l.cfg.Level(e.w.status), "request", l.args(e, fields)...)
+	default:
+		logger := l.cfg.Logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Log(e.r.Context(), l.cfg.Level(e.w.status), "request", l.args(e, fields)...)
+	}
+}
+
+// args returns the selected fields followed by the request's log
+// fields not already selected
+func (l *accessLogger) args(e accessEntry, fields []any) []any {
+	args := make([]any, 0, 2*len(l.cfg.Fields)+len(fields))
+	seen := map[string]bool{}
+
+	for _, f := range l.cfg.Fields {
+		var v any
+		switch f {
+		case FieldMethod:
+			v = e.r.Method
+		case FieldPath:
+			v = e.r.URL.Path
+		case FieldQuery:
+			v = e.r.URL.RawQuery
+		case FieldRoute:
+			v = e.route
+		case FieldStatus:
+			v = e.w.status
+		case FieldDuration:
+			v = e.duration
+		case FieldBytes:
+			v = e.w.bytes
+		case FieldUserAgent:
+			v = e.r.UserAgent()
+		case FieldReferer:
+			v = e.r.Referer()
+		case FieldRequestID:
+			v = requestIDOf(e, fields)
+		case FieldRemoteIP:
+			v = remoteIP(e.r)
+		case FieldProto:
+			v = e.r.Proto
+		case FieldHost:
+			v = e.r.Host
+		default:
+			continue
+		}
+		if s, ok := v.(string); ok && s == "" {
+			continue
+		}
+		args = append(args, string(f), v)
+		seen[string(f)] = true
+	}
+
+	for i := 0; i < len(fields); i++ {
+		switch f := fields[i].(type) {
+		case slog.Attr:
+			if !seen[f.Key] {
+				args = append(args, f)
+			}
+		case string:
+			if i+1 == len(fields) {
+				args = append(args, f)
+				break
+			}
+			i++
+			if !seen[f] {
+				args = append(args, f, fields[i])
+			}
+		default:
+			args = append(args, f)
+		}
+	}
+
+	return args
+}
+
+// requestIDOf finds the request ID in the log fields, which outer
+// middleware can see even when RequestID runs after them
+func requestIDOf(e accessEntry, fields []any) string {
+	if id := GetRequestID(e.r.Context()); id != "" {
+		return id
+	}
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] == "request_id" {
+			id, _ := fields[i+1].(string)
+			return id
+		}
+	}
+
+	return ""
+}
+
+func remoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+
+	return r.RemoteAddr
+}
+
+// combinedLine formats the Apache combined log format:
+// host ident user [time] "request" status bytes "referer" "user-agent"
+func combinedLine(e accessEntry) string {
+	user := "-"
+	if u, _, ok := e.r.BasicAuth(); ok && u != "" {
+		user = quoteLog(u, false)
+	}
+	size := "-"
+	if e.w.bytes > 0 {
+		size = strconv.FormatInt(e.w.bytes, 10)
+	}
+
+	var b strings.Builder
+	b.WriteString(remoteIP(e.r))
+	b.WriteString(" - ")
+	b.WriteString(user)
+	b.WriteString(" [")
+	b.WriteString(e.start.Format("02/Jan/2006:15:04:05 -0700"))
+	b.WriteString(`] "`)
+	b.WriteString(quoteLog(e.r.Method+" "+e.r.RequestURI+" "+e.r.Proto, true))
+	b.WriteString(`" `)
+	b.WriteString(strconv.Itoa(e.w.status))
+	b.WriteByte(' ')
+	b.WriteString(size)
+	b.WriteString(` "`)
+	b.WriteString(quoteLog(orDash(e.r.Referer()), true))
+	b.WriteString(`" "`)
+	b.WriteString(quoteLog(orDash(e.r.UserAgent()), true))
+	b.WriteString("\"\n")
+
+	return b.String()
+}
+
+// quoteLog escapes quotes, backslashes and control characters so a
+// value can't break the log line
+func quoteLog(s string, quoted bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case c == '"' && quoted, c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f || (c == ' ' && !quoted):
+			b.WriteString(`\x`)
+			b.WriteString(strconv.FormatUint(uint64(c)>>4, 16))
+			b.WriteString(strconv.FormatUint(uint64(c)&0xf, 16))
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+// lockedWriter serializes writes from concurrent requests
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	return lw.w.Write(p)
+}
@@ -0,0 +1,264 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// CORSConfig configures CORS
+type CORSConfig struct {
+	// AllowedOrigins are exact origins ("https://example.com"), origins
+	// with a wildcard subdomain ("https://*.example.com"), or "*" for
+	// any origin
+	AllowedOrigins []string
+	// AllowedOriginPatterns are regular expressions an origin must match
+	// in full, e.g. `https://pr-\d+\.preview\.example\.com`
+	AllowedOriginPatterns []string
+	// AllowOriginFunc decides for origins not allowed by the lists above
+	AllowOriginFunc func(r *http.Request, origin string) bool
+	// AllowedMethods defaults to GET, HEAD and POST
+	AllowedMethods []string
+	// AllowedHeaders are the request headers a client may send; "*"
+	// allows any. Defaults to Accept, Accept-Language, Content-Language,
+	// Content-Type and Authorization.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers scripts may read
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies and authorization
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+	// AllowPrivateNetwork answers Private Network Access preflights,
+	// letting public sites call this server on a private network
+	AllowPrivateNetwork bool
+}
+
+// cors is a compiled CORSConfig
+type cors struct {
+	cfg       CORSConfig
+	anyOrigin bool
+	origins   []string
+	wildcards [][2]string
+	patterns  []*regexp.Regexp
+	methods   []string
+	anyHeader bool
+	headers   []string
+	maxAge    string
+}
+
+// CORS handles Cross-Origin Resource Sharing. Preflight requests are
+// answered directly, allowing only the configured methods the route has
+// a handler for; other requests get the CORS headers and continue.
+// Use it on the router rather than with With, so that preflights reach
+// it before method matching.
+func CORS(cfg CORSConfig) func(http.Handler) http.Handler {
+	c := &cors{cfg: cfg}
+
+	for _, o := range cfg.AllowedOrigins {
+		o = strings.ToLower(o)
+		switch {
+		case o == "*":
+			c.anyOrigin = true
+		case strings.Contains(o, "*"):
+			prefix, suffix, _ := strings.Cut(o, "*")
+			c.wildcards = append(c.wildcards, [2]string{prefix, suffix})
+		default:
+			c.origins = append(c.origins, o)
+		}
+	}
+	for _, p := range cfg.AllowedOriginPatterns {
+		c.patterns = append(c.patterns, regexp.MustCompile("^(?:"+p+")$"))
+	}
+
+	methods := cfg.AllowedMethods
+	if methods == nil {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	for _, m := range methods {
+		c.methods = append(c.methods, strings.ToUpper(m))
+	}
+
+	headers := cfg.AllowedHeaders
+	if headers == nil {
+		headers = []string{"Accept", "Accept-Language", "Content-Language", "Content-Type", "Authorization"}
+	}
+	for _, h := range headers {
+		if h == "*" {
+			c.anyHeader = true
+			continue
+		}
+		c.headers = append(c.headers, http.CanonicalHeaderKey(h))
+	}
+
+	if cfg.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(cfg.MaxAge / time.Second))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions && r.Header.Get("Origin") != "" && r.Header.Get("Access-Control-Request-Method") != "" {
+				c.preflight(w, r)
+				return
+			}
+
+			c.actual(w, r)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// preflight answers an OPTIONS preflight request. A rejected preflight
+// gets no CORS headers, which makes the browser block the request.
+func (c *cors) preflight(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	if c.cfg.AllowPrivateNetwork {
+		h.Add("Vary", "Access-Control-Request-Private-Network")
+	}
+	defer w.WriteHeader(http.StatusNoContent)
+
+	origin := r.Header.Get("Origin")
+	if !c.originAllowed(r, origin) {
+		return
+	}
+
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	methods := c.routeMethods(r)
+	if !slices.Contains(methods, method) {
+		return
+	}
+
+	requested := parseHeaderList(r.Header.Values("Access-Control-Request-Headers"))
+	if !c.anyHeader {
+		for _, name := range requested {
+			if !slices.Contains(c.headers, name) {
+				return
+			}
+		}
+	}
+
+	c.setOrigin(h, origin)
+	h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if len(requested) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+	}
+	if c.cfg.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if c.maxAge != "" {
+		h.Set("Access-Control-Max-Age", c.maxAge)
+	}
+	if c.cfg.AllowPrivateNetwork && r.Header.Get("Access-Control-Request-Private-Network") == "true" {
+		h.Set("Access-Control-Allow-Private-Network", "true")
+	}
+}
+
+// actual sets the CORS headers of a non-preflight request
+func (c *cors) actual(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	if !c.anyOrigin || c.cfg.AllowCredentials {
+		h.Add("Vary", "Origin")
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" || !c.originAllowed(r, origin) || !slices.Contains(c.methods, r.Method) {
+		return
+	}
+
+	c.setOrigin(h, origin)
+	if c.cfg.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(c.cfg.ExposedHeaders) > 0 {
+		h.Set("Access-Control-Expose-Headers", strings.Join(c.cfg.ExposedHeaders, ", "))
+	}
+}
+
+// setOrigin allows the origin. Credentialed responses can't use "*".
+func (c *cors) setOrigin(h http.Header, origin string) {
+	if c.anyOrigin && !c.cfg.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+
+	h.Set("Access-Control-Allow-Origin", origin)
+}
+
+func (c *cors) originAllowed(r *http.Request, origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if c.anyOrigin {
+		return true
+	}
+
+	o := strings.ToLower(origin)
+	if slices.Contains(c.origins, o) {
+		return true
+	}
+	for _, w := range c.wildcards {
+		if len(o) > len(w[0])+len(w[1]) && strings.HasPrefix(o, w[0]) && strings.HasSuffix(o, w[1]) {
+			// The wildcard stands for subdomains, not for a path or port
+			if !strings.ContainsAny(o[len(w[0]):len(o)-len(w[1])], "/:@?#") {
+				return true
+			}
+		}
+	}
+	for _, p := range c.patterns {
+		if p.MatchString(origin) {
+			return true
+		}
+	}
+
+	return c.cfg.AllowOriginFunc != nil && c.cfg.AllowOriginFunc(r, origin)
+}
+
+// routeMethods returns the allowed methods the router has a route for
+// at the request path. Outside of a chi router all allowed methods are
+// returned.
+func (c *cors) routeMethods(r *http.Request) []string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return c.methods
+	}
+
+	path := rctx.RoutePath
+	if path == "" {
+		path = r.URL.RawPath
+		if path == "" {
+			path = r.URL.Path
+		}
+	}
+
+	var methods []string
+	for _, m := range c.methods {
+		if rctx.Routes.Match(chi.NewRouteContext(), m, path) {
+			methods = append(methods, m)
+		}
+	}
+
+	return methods
+}
+
+// parseHeaderList splits comma-separated header names into canonical
+// form
+func parseHeaderList(values []string) []string {
+	var names []string
+	for _, v := range values {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,275 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// corsRouter serves GET and PUT /items and GET /health behind CORS
+func corsRouter(cfg CORSConfig) http.Handler {
+	r := chi.NewRouter()
+	r.Use(CORS(cfg))
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r.Get("/items", ok)
+	r.Put("/items", ok)
+	r.Get("/health", ok)
+
+	return r
+}
+
+func corsRequest(h http.Handler, method, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestCORSOrigins(t *testing.T) {
+	cfg := CORSConfig{
+		AllowedOrigins:        []string{"https://app.example.com", "https://*.example.org"},
+		AllowedOriginPatterns: []string{`https://pr-\d+\.preview\.example\.net`},
+		AllowOriginFunc: func(r *http.Request, origin string) bool {
+			return origin == "https://partner.example"
+		},
+	}
+
+	tests := []struct {
+		origin string
+		allow  bool
+	}{
+		{"https://app.example.com", true},
+		{"HTTPS://APP.EXAMPLE.COM", true},
+		{"http://app.example.com", false},
+		{"https://app.example.com.evil.com", false},
+		{"https://api.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"https://evil.com/.example.org", false},
+		{"https://pr-42.preview.example.net", true},
+		{"https://pr-x.preview.example.net", false},
+		{"https://pr-42.preview.example.net.evil.com", false},
+		{"https://partner.example", true},
+		{"https://other.example", false},
+	}
+
+	h := corsRouter(cfg)
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			w := corsRequest(h, http.MethodGet, "/items", map[string]string{"Origin": tt.origin})
+			got := w.Header().Get("Access-Control-Allow-Origin")
+			if tt.allow && got != tt.origin || !tt.allow && got != "" {
+				t.Fatalf("Access-Control-Allow-Origin = %q, allowed %v", got, tt.allow)
+			}
+			if w.Code != http.StatusOK {
+				t.Fatalf("actual request not passed on: %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	preflight := func(path, method string, extra map[string]string) map[string]string {
+		h := map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": method}
+		for k, v := range extra {
+			h[k] = v
+		}
+		return h
+	}
+
+	tests := []struct {
+		name        string
+		cfg         CORSConfig
+		path        string
+		headers     map[string]string
+		wantMethods string
+		wantHeaders string
+		wantPNA     bool
+	}{
+		{
+			name:        "route methods only",
+			cfg:         CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET", "PUT", "DELETE"}},
+			path:        "/items",
+			headers:     preflight("/items", "PUT", nil),
+			wantMethods: "GET, PUT",
+		},
+		{
+			name:    "method the route lacks",
+			cfg:     CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET", "PUT", "DELETE"}},
+			path:    "/items",
+			headers: preflight("/items", "DELETE", nil),
+		},
+		{
+			name:    "method the route has but is not allowed",
+			cfg:     CORSConfig{AllowedOrigins: []string{"*"}},
+			path:    "/items",
+			headers: preflight("/items", "PUT", nil),
+		},
+		{
+			name:        "other route",
+			cfg:         CORSConfig{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET", "PUT"}},
+			path:        "/health",
+			headers:     preflight("/health", "GET", nil),
+			wantMethods: "GET",
+		},
+		{
+			name:    "origin not allowed",
+			cfg:     CORSConfig{AllowedOrigins: []string{"https://other.example.com"}},
+			path:    "/items",
+			headers: preflight("/items", "GET", nil),
+		},
+		{
+			name:        "allowed headers",
+			cfg:         CORSConfig{AllowedOrigins: []string{"*"}},
+			path:        "/items",
+			headers:     preflight("/items", "GET", map[string]string{"Access-Control-Request-Headers": "content-type, authorization"}),
+			wantMethods: "GET",
+			wantHeaders: "Content-Type, Authorization",
+		},
+		{
+			name:    "header not allowed",
+			cfg:     CORSConfig{AllowedOrigins: []string{"*"}},
+			path:    "/items",
+			headers: preflight("/items", "GET", map[string]string{"Access-Control-Request-Headers": "X-Custom"}),
+		},
+		{
+			name:        "any header",
+			cfg:         CORSConfig{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"*"}},
+			path:        "/items",
+			headers:     preflight("/items", "GET", map[string]string{"Access-Control-Request-Headers": "X-Custom"}),
+			wantMethods: "GET",
+			wantHeaders: "X-Custom",
+		},
+		{
+			name:        "private network allowed",
+			cfg:         CORSConfig{AllowedOrigins: []string{"*"}, AllowPrivateNetwork: true},
+			path:        "/items",
+			headers:     preflight("/items", "GET", map[string]string{"Access-Control-Request-Private-Network": "true"}),
+			wantMethods: "GET",
+			wantPNA:     true,
+		},
+		{
+			name:        "private network not allowed",
+			cfg:         CORSConfig{AllowedOrigins: []string{"*"}},
+			path:        "/items",
+			headers:     preflight("/items", "GET", map[string]string{"Access-Control-Request-Private-Network": "true"}),
+			wantMethods: "GET",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := corsRequest(corsRouter(tt.cfg), http.MethodOptions, tt.path, tt.headers)
+			h := w.Header()
+
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("status = %d, want 204", w.Code)
+			}
+			if got := h.Get("Access-Control-Allow-Methods"); got != tt.wantMethods {
+				t.Fatalf("Access-Control-Allow-Methods = %q, want %q", got, tt.wantMethods)
+			}
+			if allowed := h.Get("Access-Control-Allow-Origin") != ""; allowed != (tt.wantMethods != "") {
+				t.Fatalf("Access-Control-Allow-Origin = %q", h.Get("Access-Control-Allow-Origin"))
+			}
+			if got := h.Get("Access-Control-Allow-Headers"); got != tt.wantHeaders {
+				t.Fatalf("Access-Control-Allow-Headers = %q, want %q", got, tt.wantHeaders)
+			}
+			if got := h.Get("Access-Control-Allow-Private-Network") == "true"; got != tt.wantPNA {
+				t.Fatalf("Access-Control-Allow-Private-Network = %q", h.Get("Access-Control-Allow-Private-Network"))
+			}
+		})
+	}
+}
+
+func TestCORSCredentials(t *testing.T) {
+	h := corsRouter(CORSConfig{
+		AllowedOrigins:   []string{"*"},
+		AllowedMethods:   []string{"GET", "PUT"},
+		AllowCredentials: true,
+		ExposedHeaders:   []string{"X-Total-Count"},
+		MaxAge:           10 * time.Minute,
+	})
+	origin := "https://app.example.com"
+
+	w := corsRequest(h, http.MethodGet, "/items", map[string]string{"Origin": origin})
+	// Credentialed responses can't use "*"
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != origin {
+		t.Fatalf("Access-Control-Allow-Origin = %q, want the request origin", got)
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "true" || w.Header().Get("Access-Control-Expose-Headers") != "X-Total-Count" {
+		t.Fatalf("headers = %v", w.Header())
+	}
+
+	w = corsRequest(h, http.MethodOptions, "/items", map[string]string{"Origin": origin, "Access-Control-Request-Method": "PUT"})
+	if w.Header().Get("Access-Control-Allow-Origin") != origin || w.Header().Get("Access-Control-Allow-Credentials") != "true" || w.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Fatalf("preflight headers = %v", w.Header())
+	}
+}
+
+func TestCORSVary(t *testing.T) {
+	preflightVary := []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}
+
+	tests := []struct {
+		name    string
+		cfg     CORSConfig
+		method  string
+		headers map[string]string
+		want    []string
+	}{
+		{"any origin", CORSConfig{AllowedOrigins: []string{"*"}}, "GET", map[string]string{"Origin": "https://a.example"}, nil},
+		{"any origin with credentials", CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}, "GET", map[string]string{"Origin": "https://a.example"}, []string{"Origin"}},
+		{"listed origins", CORSConfig{AllowedOrigins: []string{"https://a.example"}}, "GET", map[string]string{"Origin": "https://a.example"}, []string{"Origin"}},
+		// Caches must not reuse a response without CORS headers for an allowed origin
+		{"rejected origin", CORSConfig{AllowedOrigins: []string{"https://a.example"}}, "GET", map[string]string{"Origin": "https://b.example"}, []string{"Origin"}},
+		{"no origin", CORSConfig{AllowedOrigins: []string{"https://a.example"}}, "GET", nil, []string{"Origin"}},
+		{"preflight", CORSConfig{AllowedOrigins: []string{"*"}}, "OPTIONS", map[string]string{"Origin": "https://a.example", "Access-Control-Request-Method": "GET"}, preflightVary},
+		{"private network preflight", CORSConfig{AllowedOrigins: []string{"*"}, AllowPrivateNetwork: true}, "OPTIONS",
+			map[string]string{"Origin": "https://a.example", "Access-Control-Request-Method": "GET"},
+			append(slices.Clone(preflightVary), "Access-Control-Request-Private-Network")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := corsRequest(corsRouter(tt.cfg), tt.method, "/items", tt.headers)
+			if got := w.Header().Values("Vary"); !slices.Equal(got, tt.want) {
+				t.Fatalf("Vary = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCORSPassesOtherRequests(t *testing.T) {
+	var reached bool
+	h := CORS(CORSConfig{AllowedOrigins: []string{"*"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+
+	// OPTIONS without Access-Control-Request-Method is not a preflight
+	corsRequest(h, http.MethodOptions, "/items", map[string]string{"Origin": "https://a.example"})
+	if !reached {
+		t.Fatal("plain OPTIONS request was not passed on")
+	}
+
+	// Outside of a chi router every allowed method is offered
+	w := corsRequest(h, http.MethodOptions, "/items", map[string]string{"Origin": "https://a.example", "Access-Control-Request-Method": "POST"})
+	if got := w.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(got, "POST") {
+		t.Fatalf("Access-Control-Allow-Methods = %q", got)
+	}
+}
+
+func TestCORSDoesNotModifyConfig(t *testing.T) {
+	methods := []string{"get", "put"}
+	CORS(CORSConfig{AllowedMethods: methods})
+	if !slices.Equal(methods, []string{"get", "put"}) {
+		t.Fatalf("AllowedMethods changed to %v", methods)
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+)
+
+type logFieldsKey struct{}
+
+// logFields collects the log attributes of a request. It is shared by
+// every middleware and handler of the request, so fields added deep in
+// a handler still reach the access log written by an outer middleware.
+type logFields struct {
+	mu   sync.Mutex
+	args []any
+}
+
+// WithLogFields returns a context able to carry log fields. A context
+// that already carries them is returned unchanged.
+func WithLogFields(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(logFieldsKey{}).(*logFields); ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, logFieldsKey{}, &logFields{})
+}
+
+// AddLogFields adds slog key-value pairs or slog.Attr values to the
+// request's log fields. It does nothing unless the context was prepared
+// with WithLogFields.
+func AddLogFields(ctx context.Context, args ...any) {
+	if f, ok := ctx.Value(logFieldsKey{}).(*logFields); ok {
+		f.mu.Lock()
+		f.args = append(f.args, args...)
+		f.mu.Unlock()
+	}
+}
+
+// LogFields returns the request's log fields, in the order they were
+// added
+func LogFields(ctx context.Context) []any {
+	f, ok := ctx.Value(logFieldsKey{}).(*logFields)
+	if !ok {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]any(nil), f.args...)
+}
@@ -1,30 +1,16 @@
 package middleware
 
 import (
-	"log/slog"
+	"bufio"
+	"net"
 	"net/http"
-	"time"
 )
 
-// Logger logs each request's method, path, status and duration via slog.
-func Logger(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
-		next.ServeHTTP(rec, r)
-		slog.Info("request",
-			"method", r.Method,
-			"path", r.URL.Path,
-			"status", rec.status,
-			"duration", time.Since(start),
-		)
-	})
-}
-
-// responseRecorder captures the status code for logging.
+// responseRecorder captures the status code and body size for logging.
 type responseRecorder struct {
 	http.ResponseWriter
 	status      int
+	bytes       int64
 	wroteHeader bool
 }
 
@@ -32,6 +18,12 @@ func (rr *responseRecorder) WriteHeader(code int) {
 	if rr.wroteHeader {
 		return
 	}
+	// Informational responses such as 103 Early Hints come before the
+	// final status, which is still to be written
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		rr.ResponseWriter.WriteHeader(code)
+		return
+	}
 	rr.status = code
 	rr.wroteHeader = true
 	rr.ResponseWriter.WriteHeader(code)
@@ -41,5 +33,30 @@ func (rr *responseRecorder) Write(b []byte) (int, error) {
 	if !rr.wroteHeader {
 		rr.WriteHeader(http.StatusOK)
 	}
-	return rr.ResponseWriter.Write(b)
+	n, err := rr.ResponseWriter.Write(b)
+	rr.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher so streaming handlers keep working
+func (rr *responseRecorder) Flush() {
+	if !rr.wroteHeader {
+		rr.WriteHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(rr.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+// Hijack lets upgraded connections, e.g. WebSockets, take over the
+// connection through the wrapper
+func (rr *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(rr.ResponseWriter).Hijack()
+	if err == nil {
+		rr.wroteHeader = true
+	}
+	return conn, rw, err
 }
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/revenkroz/si/metrics"
+)
+
+type streamGaugeKey struct{}
+
+// Metrics records request counts, latencies, in-flight requests and
+// open SSE connections in reg, or metrics.Default when reg is nil.
+// Requests are labelled by route pattern, not raw path, so the number
+// of series stays bounded; unmatched requests use "unmatched" and
+// non-standard methods "OTHER".
+func Metrics(reg *metrics.Registry) func(http.Handler) http.Handler {
+	if reg == nil {
+		reg = metrics.Default
+	}
+
+	requests := reg.NewCounterVec("http_requests_total",
+		"Total number of HTTP requests.", "method", "route", "status")
+	duration := reg.NewHistogramVec("http_request_duration_seconds",
+		"HTTP request latency in seconds.", metrics.DefBuckets, "method", "route", "status")
+	inFlight := reg.NewGauge("http_requests_in_flight",
+		"Number of HTTP requests being served.")
+	streams := reg.NewGauge("http_sse_connections",
+		"Number of open Server-Sent Events connections.")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			r = r.WithContext(context.WithValue(r.Context(), streamGaugeKey{}, streams))
+			next.ServeHTTP(rec, r)
+
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				if pattern := rctx.RoutePattern(); pattern != "" {
+					route = pattern
+				}
+			}
+			method := metricMethod(r.Method)
+			status := strconv.Itoa(rec.status)
+
+			requests.With(method, route, status).Inc()
+			duration.With(method, route, status).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// metricMethod returns the method label, folding methods a client can
+// make up into "OTHER"
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+
+	return "OTHER"
+}
+
+// TrackStream counts a long-lived streaming response, such as an SSE
+// connection, until done is called. It does nothing without Metrics.
+func TrackStream(r *http.Request) (done func()) {
+	g, ok := r.Context().Value(streamGaugeKey{}).(*metrics.Gauge)
+	if !ok {
+		return func() {}
+	}
+
+	g.Inc()
+	return g.Dec
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/revenkroz/si/metrics"
+)
+
+func TestMetricsMethodLabel(t *testing.T) {
+	reg := metrics.NewRegistry()
+	h := Metrics(reg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, method := range []string{"GET", "DELETE", "BREW", "PROPFIND", "X-" + strings.Repeat("A", 64)} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/items", nil))
+	}
+
+	requests := reg.NewCounterVec("http_requests_total", "Total number of HTTP requests.", "method", "route", "status")
+	for method, want := range map[string]float64{"GET": 1, "DELETE": 1, "OTHER": 3, "BREW": 0} {
+		if got := requests.With(method, "unmatched", "200").Value(); got != want {
+			t.Errorf("requests with method %q = %v, want %v", method, got, want)
+		}
+	}
+}
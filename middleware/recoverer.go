@@ -6,47 +6,124 @@ package middleware
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"runtime/debug"
 	"strings"
 )
 
+// RecovererConfig configures RecovererWithConfig
+type RecovererConfig struct {
+	// OnPanic is called with the recovered value and the stack, e.g. to
+	// report the panic to an error tracker
+	OnPanic func(r *http.Request, rvr any, stack []byte)
+	// Render writes the response to a recovered request. Defaults to a
+	// bare 500. It isn't called when the response has already started.
+	Render func(w http.ResponseWriter, r *http.Request, rvr any)
+	// Logger logs the panic and stack as a structured record instead of
+	// printing the stack to RecovererErrorWriter
+	Logger *slog.Logger
+}
+
+type recovererKey struct{}
+
 // Recoverer is a middleware that recovers from panics, logs the panic (and a
 // backtrace), and returns HTTP 500 (Internal Server Error) status if possible.
 func Recoverer(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if rvr := recover(); rvr != nil {
-				if rvr == http.ErrAbortHandler {
-					// We don't recover http.ErrAbortHandler so the response
-					// to the client is aborted. This should not be logged.
-					panic(rvr)
-				}
+	return defaultRecoverer(next)
+}
+
+var defaultRecoverer = RecovererWithConfig(RecovererConfig{})
 
-				PrintPrettyStack(rvr)
+// RecovererWithConfig is Recoverer with a panic callback, a custom
+// response and structured logging. When the response has already
+// started, the connection is aborted instead, so the client can't
+// mistake the partial response for a complete one.
+func RecovererWithConfig(cfg RecovererConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			r = r.WithContext(context.WithValue(WithLogFields(r.Context()), recovererKey{}, &cfg))
 
-				if r.Header.Get("Connection") != "Upgrade" {
-					w.WriteHeader(http.StatusInternalServerError)
+			defer func() {
+				if rvr := recover(); rvr != nil {
+					if rvr == http.ErrAbortHandler {
+						// We don't recover http.ErrAbortHandler so the response
+						// to the client is aborted. This should not be logged.
+						panic(rvr)
+					}
+
+					cfg.report(r, rvr, debug.Stack(), rec.wroteHeader)
+
+					if r.Header.Get("Connection") == "Upgrade" {
+						return
+					}
+					if rec.wroteHeader {
+						panic(http.ErrAbortHandler)
+					}
+					if cfg.Render != nil {
+						cfg.Render(rec, r, rvr)
+					} else {
+						rec.WriteHeader(http.StatusInternalServerError)
+					}
 				}
-			}
-		}()
+			}()
 
-		next.ServeHTTP(w, r)
-	})
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// ReportPanic reports a panic recovered outside of the handler, e.g. in
+// a goroutine it started, like the Recoverer in front of the handler
+// would
+func ReportPanic(r *http.Request, rvr any, stack []byte) {
+	cfg, ok := r.Context().Value(recovererKey{}).(*RecovererConfig)
+	if !ok {
+		cfg = &RecovererConfig{}
+	}
+
+	cfg.report(r, rvr, stack, false)
+}
+
+func (cfg *RecovererConfig) report(r *http.Request, rvr any, stack []byte, started bool) {
+	if cfg.OnPanic != nil {
+		cfg.OnPanic(r, rvr, stack)
+	}
+
+	if cfg.Logger == nil {
+		printPrettyStack(rvr, stack)
+		return
+	}
+
+	args := []any{
+		"panic", fmt.Sprint(rvr),
+		"method", r.Method,
+		"path", r.URL.Path,
+	}
+	if started {
+		args = append(args, "response_started", true)
+	}
+	args = append(args, "stack", string(stack))
+	cfg.Logger.Log(r.Context(), slog.LevelError, "panic recovered", append(args, LogFields(r.Context())...)...)
 }
 
 // RecovererErrorWriter is the writer used by PrintPrettyStack.
 // Defaults to os.Stderr. Can be replaced for testing.
 var RecovererErrorWriter io.Writer = os.Stderr
 
-// PrintPrettyStack prints a human-readable, coloured stack trace to
-// RecovererErrorWriter.
+// PrintPrettyStack prints a human-readable stack trace to
+// RecovererErrorWriter, coloured when stdout is a terminal.
 func PrintPrettyStack(rvr interface{}) {
-	debugStack := debug.Stack()
+	printPrettyStack(rvr, debug.Stack())
+}
+
+func printPrettyStack(rvr any, debugStack []byte) {
 	s := prettyStack{}
 	out, err := s.parse(debugStack, rvr)
 	if err == nil {
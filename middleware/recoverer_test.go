@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"testing"
+)
+
+func TestRecovererInformationalResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    int
+	}{
+		{"final status after early hints", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Link", "</app.css>; rel=preload; as=style")
+			w.WriteHeader(http.StatusEarlyHints)
+			w.WriteHeader(http.StatusCreated)
+		}, http.StatusCreated},
+		{"implicit 200 after early hints", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusEarlyHints)
+			_, _ = io.WriteString(w, "ok")
+		}, http.StatusOK},
+		{"panic after early hints", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusEarlyHints)
+			panic("boom")
+		}, http.StatusInternalServerError},
+	}
+
+	stderr := RecovererErrorWriter
+	RecovererErrorWriter = io.Discard
+	t.Cleanup(func() { RecovererErrorWriter = stderr })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(Recoverer(tt.handler))
+			defer srv.Close()
+
+			var hints int
+			trace := &httptrace.ClientTrace{Got1xxResponse: func(code int, _ textproto.MIMEHeader) error {
+				if code == http.StatusEarlyHints {
+					hints++
+				}
+				return nil
+			}}
+			req, _ := http.NewRequestWithContext(httptrace.WithClientTrace(t.Context(), trace), http.MethodGet, srv.URL, nil)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.want || hints != 1 {
+				t.Fatalf("status = %d with %d early hints, want %d after one", resp.StatusCode, hints, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseRecorderStatus(t *testing.T) {
+	rec := &responseRecorder{ResponseWriter: httptest.NewRecorder(), status: http.StatusOK}
+	rec.WriteHeader(http.StatusEarlyHints)
+	if rec.wroteHeader {
+		t.Fatal("103 recorded as the final status")
+	}
+	rec.WriteHeader(http.StatusAccepted)
+	rec.WriteHeader(http.StatusInternalServerError)
+	if rec.status != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202", rec.status)
+	}
+}
@@ -1,23 +1,121 @@
 package middleware
 
 import (
+	"context"
 	"crypto/rand"
+	"encoding/binary"
 	"fmt"
 	"net/http"
+	"time"
 )
 
+// RequestIDHeader is the default request ID header
+const RequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+// requestID is the request ID and the header it travels in
+type requestID struct {
+	id     string
+	header string
+}
+
+// RequestIDConfig configures RequestIDWithConfig
+type RequestIDConfig struct {
+	// Header carries the ID in requests and responses. Defaults to
+	// X-Request-Id.
+	Header string
+	// TrustIncoming reuses the ID of an incoming request when it is
+	// valid. Otherwise a new ID is always generated.
+	TrustIncoming bool
+	// MaxLength is the longest incoming ID accepted. Defaults to 128.
+	MaxLength int
+	// Valid checks the characters of an incoming ID. Defaults to
+	// ValidRequestID.
+	Valid func(id string) bool
+	// Generator creates new IDs, e.g. UUIDv7 or ULID. Defaults to 32
+	// random hex characters.
+	Generator func() string
+}
+
 // RequestID generates a unique request ID and stores it in the
-// X-Request-Id response header. If the incoming request already
-// carries the header, its value is reused.
+// X-Request-Id response header, the request context and the request's
+// log fields. If the incoming request already carries a valid ID, it
+// is reused.
 func RequestID(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		id := r.Header.Get("X-Request-Id")
-		if id == "" {
-			id = generateID()
+	return defaultRequestID(next)
+}
+
+var defaultRequestID = RequestIDWithConfig(RequestIDConfig{TrustIncoming: true})
+
+// RequestIDWithConfig is RequestID with a custom header, trust policy,
+// validation and generator
+func RequestIDWithConfig(cfg RequestIDConfig) func(http.Handler) http.Handler {
+	if cfg.Header == "" {
+		cfg.Header = RequestIDHeader
+	}
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = 128
+	}
+	if cfg.Valid == nil {
+		cfg.Valid = ValidRequestID
+	}
+	if cfg.Generator == nil {
+		cfg.Generator = generateID
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var id string
+			if cfg.TrustIncoming {
+				id = r.Header.Get(cfg.Header)
+				if len(id) > cfg.MaxLength || !cfg.Valid(id) {
+					id = ""
+				}
+			}
+			if id == "" {
+				id = cfg.Generator()
+			}
+			w.Header().Set(cfg.Header, id)
+
+			ctx := context.WithValue(WithLogFields(r.Context()), requestIDKey{}, requestID{id: id, header: cfg.Header})
+			AddLogFields(ctx, "request_id", id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetRequestID returns the request ID set by RequestID, or ""
+func GetRequestID(ctx context.Context) string {
+	rid, _ := ctx.Value(requestIDKey{}).(requestID)
+	return rid.id
+}
+
+// GetRequestIDHeader returns the header the request ID was configured
+// with, or "" without RequestID. Outgoing requests use it to forward
+// the ID.
+func GetRequestIDHeader(ctx context.Context) string {
+	rid, _ := ctx.Value(requestIDKey{}).(requestID)
+	return rid.header
+}
+
+// ValidRequestID accepts non-empty IDs made of letters, digits and
+// - _ . : + / = @
+func ValidRequestID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '+', c == '/', c == '=', c == '@':
+		default:
+			return false
 		}
-		w.Header().Set("X-Request-Id", id)
-		next.ServeHTTP(w, r)
-	})
+	}
+
+	return true
 }
 
 // generateID produces a random 16-byte hex string.
@@ -26,3 +124,84 @@ func generateID() string {
 	_, _ = rand.Read(b)
 	return fmt.Sprintf("%x", b)
 }
+
+// UUIDv4 generates a random UUID (RFC 9562)
+func UUIDv4() string {
+	var u [16]byte
+	_, _ = rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+
+	return formatUUID(u)
+}
+
+// UUIDv7 generates a time-ordered UUID (RFC 9562): a millisecond
+// timestamp followed by random bits
+func UUIDv7() string {
+	var u [16]byte
+	_, _ = rand.Read(u[6:])
+	ms := uint64(time.Now().UnixMilli())
+	u[0], u[1], u[2] = byte(ms>>40), byte(ms>>32), byte(ms>>24)
+	u[3], u[4], u[5] = byte(ms>>16), byte(ms>>8), byte(ms)
+	u[6] = u[6]&0x0f | 0x70
+	u[8] = u[8]&0x3f | 0x80
+
+	return formatUUID(u)
+}
+
+func formatUUID(u [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID generates a lexicographically sortable ID
+// (https://github.com/ulid/spec): a millisecond timestamp and 80
+// random bits in 26 Crockford base32 characters
+func ULID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[6:])
+	ms := uint64(time.Now().UnixMilli())
+	binary.BigEndian.PutUint16(b[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(b[2:6], uint32(ms))
+
+	// 128 bits in 26 characters of 5 bits, the first one holding 3
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(out)
+}
+
+const (
+	base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// ksuidEpoch is the KSUID epoch, 2014-05-13T16:53:20Z
+	ksuidEpoch = 1400000000
+)
+
+// KSUID generates a K-Sortable Unique ID (https://github.com/segmentio/ksuid):
+// a timestamp in seconds and 128 random bits in 27 base62 characters
+func KSUID() string {
+	var b [20]byte
+	binary.BigEndian.PutUint32(b[:4], uint32(time.Now().Unix()-ksuidEpoch))
+	_, _ = rand.Read(b[4:])
+
+	// Long division of the 160-bit number by 62
+	out := make([]byte, 27)
+	num := b[:]
+	for i := 26; i >= 0; i-- {
+		var rem uint
+		for j := range num {
+			acc := rem<<8 | uint(num[j])
+			num[j] = byte(acc / 62)
+			rem = acc % 62
+		}
+		out[i] = base62[rem]
+	}
+
+	return string(out)
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/revenkroz/si/tracing"
+)
+
+// Tracing starts a server span for each request, continuing the trace
+// of an incoming traceparent header. The span is named after the route
+// pattern and records the status; 5xx responses mark it as failed. The
+// trace ID is added to the request's log fields.
+func Tracing(t *tracing.Tracer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := WithLogFields(r.Context())
+			if sc := tracing.Extract(r.Header); sc.IsValid() {
+				ctx = tracing.ContextWithRemoteSpanContext(ctx, sc)
+			}
+
+			scheme := "http"
+			if r.TLS != nil {
+				scheme = "https"
+			}
+			ctx, span := t.Start(ctx, r.Method, tracing.SpanKindServer,
+				tracing.Attr("http.request.method", r.Method),
+				tracing.Attr("url.path", r.URL.Path),
+				tracing.Attr("url.scheme", scheme),
+				tracing.Attr("server.address", r.Host),
+				tracing.Attr("network.protocol.version", protocolVersion(r)),
+			)
+			defer span.End()
+			AddLogFields(ctx, "trace_id", span.SpanContext().TraceID.String())
+			if ua := r.UserAgent(); ua != "" {
+				span.SetAttribute("user_agent.original", ua)
+			}
+			if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+				span.SetAttribute("client.address", host)
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			r = r.WithContext(ctx)
+			next.ServeHTTP(rec, r)
+
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				if pattern := rctx.RoutePattern(); pattern != "" {
+					span.SetName(r.Method + " " + pattern)
+					span.SetAttribute("http.route", pattern)
+				}
+			}
+			span.SetAttribute("http.response.status_code", rec.status)
+			if rec.status >= 500 {
+				span.SetStatus(tracing.StatusError, http.StatusText(rec.status))
+			}
+		})
+	}
+}
+
+func protocolVersion(r *http.Request) string {
+	if r.ProtoMajor >= 2 {
+		return strconv.Itoa(r.ProtoMajor)
+	}
+
+	return strconv.Itoa(r.ProtoMajor) + "." + strconv.Itoa(r.ProtoMinor)
+}
@@ -0,0 +1,226 @@
+package si
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// acceptRange is a single element of an Accept-* header
+type acceptRange struct {
+	value  string
+	params map[string]string
+	q      float64
+}
+
+// parseAccept parses an Accept-style header into its ranges.
+// Elements with an invalid q-value are ignored.
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+
+	for _, part := range strings.Split(header, ",") {
+		value, rest, _ := strings.Cut(part, ";")
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+
+		ar := acceptRange{value: value, q: 1}
+		valid := true
+		for _, param := range strings.Split(rest, ";") {
+			key, val, _ := strings.Cut(param, "=")
+			key = strings.ToLower(strings.TrimSpace(key))
+			val = strings.Trim(strings.TrimSpace(val), `"`)
+			if key == "" {
+				continue
+			}
+
+			if key == "q" {
+				q, err := strconv.ParseFloat(val, 64)
+				if err != nil || q < 0 || q > 1 {
+					valid = false
+					break
+				}
+				ar.q = q
+				// parameters after q are accept-ext, not media type parameters
+				break
+			}
+
+			if ar.params == nil {
+				ar.params = map[string]string{}
+			}
+			ar.params[key] = strings.ToLower(val)
+		}
+
+		if valid {
+			ranges = append(ranges, ar)
+		}
+	}
+
+	return ranges
+}
+
+// matchFunc returns the specificity with which a range matches an offer,
+// or -1 when it does not match
+type matchFunc func(ar acceptRange, offer string) int
+
+// negotiate returns the offer with the highest q-value. Ties are broken
+// by the specificity of the matching range, then by the order of offers.
+// An empty header accepts the first offer.
+func negotiate(header string, offers []string, match matchFunc) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if strings.TrimSpace(header) == "" {
+		return offers[0]
+	}
+
+	ranges := parseAccept(header)
+
+	best, bestQ, bestSpec := "", 0.0, -1
+	for _, offer := range offers {
+		q, spec := 0.0, -1
+		for _, ar := range ranges {
+			if s := match(ar, strings.ToLower(offer)); s > spec {
+				q, spec = ar.q, s
+			}
+		}
+
+		if q > bestQ || (q == bestQ && q > 0 && spec > bestSpec) {
+			best, bestQ, bestSpec = offer, q, spec
+		}
+	}
+
+	return best
+}
+
+// matchMediaType matches media ranges such as "text/*;q=0.5"
+func matchMediaType(ar acceptRange, offer string) int {
+	offerType, offerParams, _ := strings.Cut(offer, ";")
+	offerType = strings.TrimSpace(offerType)
+
+	if ar.value == "*/*" || ar.value == "*" {
+		return 0
+	}
+
+	rangeMain, rangeSub, _ := strings.Cut(ar.value, "/")
+	offerMain, offerSub, _ := strings.Cut(offerType, "/")
+	if rangeMain != offerMain {
+		return -1
+	}
+	if rangeSub == "*" {
+		return 1
+	}
+	if rangeSub != offerSub {
+		return -1
+	}
+
+	// Every range parameter must be present on the offer
+	for key, val := range ar.params {
+		found := false
+		for _, param := range strings.Split(offerParams, ";") {
+			k, v, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(k) == key && strings.Trim(strings.TrimSpace(v), `"`) == val {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return -1
+		}
+	}
+
+	return 2 + len(ar.params)
+}
+
+// matchLanguage matches language ranges using RFC 4647 basic filtering:
+// "en" matches "en" and "en-US"
+func matchLanguage(ar acceptRange, offer string) int {
+	if ar.value == "*" {
+		return 0
+	}
+	if ar.value == offer || strings.HasPrefix(offer, ar.value+"-") {
+		return len(ar.value)
+	}
+
+	return -1
+}
+
+// matchToken matches codings and charsets, where "*" matches anything
+func matchToken(ar acceptRange, offer string) int {
+	if ar.value == "*" {
+		return 0
+	}
+	if ar.value == offer {
+		return 1
+	}
+
+	return -1
+}
+
+// Negotiate returns the best offer for the request's Accept header,
+// honouring q-values and range specificity, or an empty string when
+// none of the offers is acceptable
+func (ctx *Context) Negotiate(offers ...string) string {
+	return negotiate(ctx.HeaderString("Accept"), offers, matchMediaType)
+}
+
+// NegotiateLanguage returns the best offer for the Accept-Language header
+func (ctx *Context) NegotiateLanguage(offers ...string) string {
+	return negotiate(ctx.HeaderString("Accept-Language"), offers, matchLanguage)
+}
+
+// NegotiateEncoding returns the best offer for the Accept-Encoding header
+func (ctx *Context) NegotiateEncoding(offers ...string) string {
+	header := ctx.HeaderString("Accept-Encoding")
+	if strings.TrimSpace(header) == "" {
+		return negotiate("", offers, matchToken)
+	}
+
+	// identity is implicitly acceptable, with the lowest preference,
+	// unless it is covered by an explicit "identity" or "*" range
+	identityListed := false
+	for _, ar := range parseAccept(header) {
+		if ar.value == "identity" || ar.value == "*" {
+			identityListed = true
+			break
+		}
+	}
+	if !identityListed {
+		header += ", identity;q=0.001"
+	}
+
+	return negotiate(header, offers, matchToken)
+}
+
+// NegotiateCharset returns the best offer for the Accept-Charset header
+func (ctx *Context) NegotiateCharset(offers ...string) string {
+	return negotiate(ctx.HeaderString("Accept-Charset"), offers, matchToken)
+}
+
+// shortMediaTypes lists short names whose media types
+// mime.TypeByExtension doesn't cover on its own
+var shortMediaTypes = map[string][]string{
+	"xml":  {"application/xml", "text/xml"},
+	"yaml": {"application/yaml", "application/x-yaml", "text/yaml"},
+	"js":   {"text/javascript", "application/javascript"},
+	"text": {"text/plain"},
+}
+
+// mediaTypeOffers turns a short name like "json" into its media types,
+// without parameters such as charset
+func mediaTypeOffers(offer string) []string {
+	if strings.Contains(offer, "/") {
+		return []string{offer}
+	}
+	if mts, ok := shortMediaTypes[offer]; ok {
+		return mts
+	}
+	if mt := mime.TypeByExtension("." + offer); mt != "" {
+		if base, _, err := mime.ParseMediaType(mt); err == nil {
+			return []string{base}
+		}
+	}
+
+	return []string{offer}
+}
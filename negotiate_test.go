@@ -0,0 +1,146 @@
+package si
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func negotiateCtx(header, value string) *Context {
+	req := httptest.NewRequest("GET", "/", nil)
+	if value != "" {
+		req.Header.Set(header, value)
+	}
+
+	return Si(req, httptest.NewRecorder())
+}
+
+func TestNegotiate(t *testing.T) {
+	json, html, xml := "application/json", "text/html", "application/xml"
+
+	tests := []struct {
+		name   string
+		accept string
+		offers []string
+		want   string
+	}{
+		{"no header takes the first offer", "", []string{json, html}, json},
+		{"exact", "text/html", []string{json, html}, html},
+		{"highest q", "application/json;q=0.5, text/html;q=0.8", []string{json, html}, html},
+		{"offer order breaks ties", "application/json, text/html", []string{html, json}, html},
+		{"specific range beats wildcard", "*/*;q=0.1, application/json", []string{html, json}, json},
+		{"subtype wildcard", "text/*", []string{json, html}, html},
+		{"any", "*/*", []string{xml, json}, xml},
+		{"q=0 excludes", "text/html;q=0, */*", []string{html, json}, json},
+		{"most specific range sets q", "text/*;q=0.9, text/html;q=0.1, */*;q=0.5", []string{"text/html", "text/plain", json}, "text/plain"},
+		{"none acceptable", "image/png", []string{json, html}, ""},
+		{"only q=0", "application/json;q=0", []string{json}, ""},
+		{"case insensitive", "Text/HTML", []string{html}, html},
+		{"media type parameters", "text/plain;format=flowed", []string{"text/plain", "text/plain;format=flowed"}, "text/plain;format=flowed"},
+		{"parameters must match", "text/plain;format=fixed", []string{"text/plain;format=flowed"}, ""},
+		{"accept-ext after q is ignored", "text/html;q=0.5;level=1, application/json;q=0.4", []string{json, html}, html},
+		{"invalid q ignores the range", "text/html;q=2, application/json;q=0.1", []string{html, json}, json},
+		{"quoted parameter", `text/plain;format="flowed"`, []string{"text/plain;format=flowed"}, "text/plain;format=flowed"},
+		{"whitespace and empty elements", " , application/json ;  q=0.7 ,", []string{json}, json},
+		{"no offers", "*/*", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := negotiateCtx("Accept", tt.accept).Negotiate(tt.offers...); got != tt.want {
+				t.Fatalf("Negotiate(%q) = %q, want %q", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		offers []string
+		want   string
+	}{
+		{"", []string{"en", "de"}, "en"},
+		{"de", []string{"en", "de-DE"}, "de-DE"},
+		{"de-DE", []string{"en", "de"}, ""},
+		{"fr;q=0.8, en-US, en;q=0.5", []string{"en-GB", "en-US", "fr"}, "en-US"},
+		{"fr;q=0.8, en;q=0.9", []string{"fr", "en-GB"}, "en-GB"},
+		{"*;q=0.1, de", []string{"en", "de"}, "de"},
+		{"*, en;q=0", []string{"en", "de"}, "de"},
+		{"EN-us", []string{"en-US"}, "en-US"},
+	}
+
+	for _, tt := range tests {
+		if got := negotiateCtx("Accept-Language", tt.header).NegotiateLanguage(tt.offers...); got != tt.want {
+			t.Errorf("NegotiateLanguage(%q, %v) = %q, want %q", tt.header, tt.offers, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		offers []string
+		want   string
+	}{
+		{"", []string{"gzip", "identity"}, "gzip"},
+		{"gzip, br;q=0.9", []string{"br", "gzip", "identity"}, "gzip"},
+		{"br;q=0.5, gzip;q=0.5", []string{"br", "gzip"}, "br"},
+		// identity stays acceptable unless excluded
+		{"br", []string{"gzip", "identity"}, "identity"},
+		{"br, identity;q=0", []string{"gzip", "identity"}, ""},
+		{"*;q=0", []string{"gzip", "identity"}, ""},
+		{"*", []string{"zstd"}, "zstd"},
+		{"gzip;q=0, *", []string{"gzip", "br"}, "br"},
+	}
+
+	for _, tt := range tests {
+		if got := negotiateCtx("Accept-Encoding", tt.header).NegotiateEncoding(tt.offers...); got != tt.want {
+			t.Errorf("NegotiateEncoding(%q, %v) = %q, want %q", tt.header, tt.offers, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiateCharset(t *testing.T) {
+	tests := []struct {
+		header string
+		offers []string
+		want   string
+	}{
+		{"", []string{"utf-8", "iso-8859-1"}, "utf-8"},
+		{"iso-8859-1, utf-8;q=0.7", []string{"utf-8", "iso-8859-1"}, "iso-8859-1"},
+		{"UTF-8", []string{"utf-8"}, "utf-8"},
+		{"*;q=0.5, utf-8;q=0", []string{"utf-8", "iso-8859-1"}, "iso-8859-1"},
+	}
+
+	for _, tt := range tests {
+		if got := negotiateCtx("Accept-Charset", tt.header).NegotiateCharset(tt.offers...); got != tt.want {
+			t.Errorf("NegotiateCharset(%q, %v) = %q, want %q", tt.header, tt.offers, got, tt.want)
+		}
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	tests := []struct {
+		accept string
+		offers []string
+		want   bool
+	}{
+		{"application/xml", []string{"xml"}, true},
+		{"text/xml", []string{"xml"}, true},
+		{"application/json", []string{"xml"}, false},
+		{"application/json", []string{"xml", "json"}, true},
+		{"text/html;q=0.9", []string{"html"}, true},
+		{"text/plain", []string{"text"}, true},
+		{"application/x-yaml", []string{"yaml"}, true},
+		{"image/*", []string{"png"}, true},
+		{"image/*", []string{"html"}, false},
+		{"text/*;q=0", []string{"html"}, false},
+		{"application/json", []string{"application/json"}, true},
+	}
+
+	for _, tt := range tests {
+		if got := negotiateCtx("Accept", tt.accept).Accepts(tt.offers...); got != tt.want {
+			t.Errorf("Accepts(%v) with Accept %q = %v, want %v", tt.offers, tt.accept, got, tt.want)
+		}
+	}
+}
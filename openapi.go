@@ -0,0 +1,556 @@
+package si
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// OpenAPIVersion is the version of generated documents
+const OpenAPIVersion = "3.1.0"
+
+// OpenAPI is an OpenAPI 3.x document
+type OpenAPI struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       OpenAPIInfo           `json:"info"`
+	Servers    []OpenAPIServer       `json:"servers,omitempty"`
+	Paths      map[string]*PathItem  `json:"paths"`
+	Components *Components           `json:"components,omitempty"`
+	Security   []SecurityRequirement `json:"security,omitempty"`
+	Tags       []OpenAPITag          `json:"tags,omitempty"`
+}
+
+// OpenAPIInfo describes the API
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIServer is a server the API is available on
+type OpenAPIServer struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPITag describes a tag used by operations
+type OpenAPITag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations available on a path
+type PathItem struct {
+	Summary     string       `json:"summary,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Parameters  []*Parameter `json:"parameters,omitempty"`
+	Get         *Operation   `json:"get,omitempty"`
+	Put         *Operation   `json:"put,omitempty"`
+	Post        *Operation   `json:"post,omitempty"`
+	Delete      *Operation   `json:"delete,omitempty"`
+	Options     *Operation   `json:"options,omitempty"`
+	Head        *Operation   `json:"head,omitempty"`
+	Patch       *Operation   `json:"patch,omitempty"`
+	Trace       *Operation   `json:"trace,omitempty"`
+}
+
+// Operation returns the operation for an HTTP method
+func (p *PathItem) Operation(method string) *Operation {
+	switch method {
+	case http.MethodGet:
+		return p.Get
+	case http.MethodPut:
+		return p.Put
+	case http.MethodPost:
+		return p.Post
+	case http.MethodDelete:
+		return p.Delete
+	case http.MethodOptions:
+		return p.Options
+	case http.MethodHead:
+		return p.Head
+	case http.MethodPatch:
+		return p.Patch
+	case http.MethodTrace:
+		return p.Trace
+	}
+
+	return nil
+}
+
+// SetOperation sets the operation for an HTTP method.
+// Methods OpenAPI cannot describe are ignored.
+func (p *PathItem) SetOperation(method string, op *Operation) {
+	switch method {
+	case http.MethodGet:
+		p.Get = op
+	case http.MethodPut:
+		p.Put = op
+	case http.MethodPost:
+		p.Post = op
+	case http.MethodDelete:
+		p.Delete = op
+	case http.MethodOptions:
+		p.Options = op
+	case http.MethodHead:
+		p.Head = op
+	case http.MethodPatch:
+		p.Patch = op
+	case http.MethodTrace:
+		p.Trace = op
+	}
+}
+
+// Operation describes a single API operation on a path
+type Operation struct {
+	OperationID string                `json:"operationId,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []SecurityRequirement `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+// Parameter describes a path, query, header or cookie parameter
+type Parameter struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Deprecated  bool    `json:"deprecated,omitempty"`
+	Explode     *bool   `json:"explode,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody describes an operation's request body
+type RequestBody struct {
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// Response describes a single response of an operation
+type Response struct {
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema for a media type
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Components holds reusable objects referenced from the document
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	Parameters      map[string]*Parameter      `json:"parameters,omitempty"`
+	RequestBodies   map[string]*RequestBody    `json:"requestBodies,omitempty"`
+	Responses       map[string]*Response       `json:"responses,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme describes an authentication scheme
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// SecurityRequirement maps security scheme names to required scopes
+type SecurityRequirement map[string][]string
+
+// Schema is a JSON Schema object as used by OpenAPI 3.1
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 SchemaType         `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Example              any                `json:"example,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	UniqueItems          bool               `json:"uniqueItems,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Not                  *Schema            `json:"not,omitempty"`
+	ReadOnly             bool               `json:"readOnly,omitempty"`
+	WriteOnly            bool               `json:"writeOnly,omitempty"`
+	Deprecated           bool               `json:"deprecated,omitempty"`
+	// Nullable is the OpenAPI 3.0 way to allow null.
+	// 3.1 documents list "null" in Type instead.
+	Nullable bool `json:"nullable,omitempty"`
+}
+
+// UnmarshalJSON accepts boolean schemas and the OpenAPI 3.0 boolean form
+// of exclusiveMinimum and exclusiveMaximum
+func (s *Schema) UnmarshalJSON(b []byte) error {
+	switch string(bytes.TrimSpace(b)) {
+	case "true":
+		*s = Schema{}
+		return nil
+	case "false":
+		*s = Schema{Not: &Schema{}}
+		return nil
+	}
+
+	type schemaAlias Schema
+	aux := struct {
+		*schemaAlias
+		ExclusiveMinimum json.RawMessage `json:"exclusiveMinimum"`
+		ExclusiveMaximum json.RawMessage `json:"exclusiveMaximum"`
+	}{schemaAlias: (*schemaAlias)(s)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	var err error
+	s.ExclusiveMinimum, s.Minimum, err = exclusiveBound(aux.ExclusiveMinimum, s.Minimum)
+	if err != nil {
+		return err
+	}
+	s.ExclusiveMaximum, s.Maximum, err = exclusiveBound(aux.ExclusiveMaximum, s.Maximum)
+
+	return err
+}
+
+// exclusiveBound converts an exclusive bound to the 3.1 numeric form.
+// In 3.0 it is a boolean that makes the inclusive bound exclusive.
+func exclusiveBound(raw json.RawMessage, inclusive *float64) (exclusive, newInclusive *float64, err error) {
+	switch string(bytes.TrimSpace(raw)) {
+	case "", "null", "false":
+		return nil, inclusive, nil
+	case "true":
+		return inclusive, nil, nil
+	}
+
+	var f float64
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, nil, err
+	}
+
+	return &f, inclusive, nil
+}
+
+// SchemaType is the "type" keyword, a single type or a list of types
+type SchemaType []string
+
+// MarshalJSON writes a single type as a string
+func (t SchemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+
+	return json.Marshal([]string(t))
+}
+
+// UnmarshalJSON accepts both a string and a list of strings
+func (t *SchemaType) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*t = SchemaType{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*t = list
+
+	return nil
+}
+
+// Has reports whether the schema type includes name
+func (t SchemaType) Has(name string) bool {
+	for _, n := range t {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OpenAPIConfig configures ServeOpenAPI
+type OpenAPIConfig struct {
+	Info            OpenAPIInfo
+	Servers         []OpenAPIServer
+	Tags            []OpenAPITag
+	SecuritySchemes map[string]*SecurityScheme
+	// Security is applied to every operation without its own requirements
+	Security []SecurityRequirement
+	// Path is where the viewer is served; the document is served at
+	// Path+"/openapi.json" and Path+"/openapi.yaml". Defaults to "/docs".
+	Path string
+}
+
+// OpenAPI generates a document describing the routes registered on r
+// and its subrouters
+func (r *Router) OpenAPI(cfg OpenAPIConfig) *OpenAPI {
+	g := newOpenAPIGenerator()
+
+	doc := &OpenAPI{
+		OpenAPI:  OpenAPIVersion,
+		Info:     cfg.Info,
+		Servers:  cfg.Servers,
+		Tags:     cfg.Tags,
+		Security: cfg.Security,
+		Paths:    map[string]*PathItem{},
+	}
+	if doc.Info.Title == "" {
+		doc.Info.Title = "API"
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = "0.0.0"
+	}
+
+	for _, route := range r.allRoutes() {
+		if route.doc.hidden {
+			continue
+		}
+
+		path, pathParams := openAPIPath(route.Pattern())
+		item := doc.Paths[path]
+		if item == nil {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		item.SetOperation(route.method, g.operation(route, pathParams))
+	}
+
+	if len(g.schemas) > 0 || len(cfg.SecuritySchemes) > 0 {
+		doc.Components = &Components{
+			SecuritySchemes: cfg.SecuritySchemes,
+		}
+		if len(g.schemas) > 0 {
+			doc.Components.Schemas = g.schemas
+		}
+	}
+
+	return doc
+}
+
+// allRoutes returns the routes of r and its subrouters
+func (r *Router) allRoutes() []*Route {
+	routes := append([]*Route(nil), r.routes...)
+	for _, child := range r.children {
+		routes = append(routes, child.allRoutes()...)
+	}
+
+	return routes
+}
+
+// openAPIPath converts a chi pattern to an OpenAPI path template and
+// returns its parameters with their regexp constraints
+func openAPIPath(pattern string) (string, []pathParam) {
+	var params []pathParam
+	b := &strings.Builder{}
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '{':
+			depth, end := 0, len(pattern)-1
+			for j := i; j < len(pattern); j++ {
+				if pattern[j] == '{' {
+					depth++
+				} else if pattern[j] == '}' {
+					depth--
+					if depth == 0 {
+						end = j
+						break
+					}
+				}
+			}
+			key, rx, _ := strings.Cut(pattern[i+1:end], ":")
+			params = append(params, pathParam{name: key, pattern: rx})
+			b.WriteString("{" + key + "}")
+			i = end
+		case c == '*' && i == len(pattern)-1:
+			params = append(params, pathParam{name: "wildcard"})
+			b.WriteString("{wildcard}")
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String(), params
+}
+
+type pathParam struct {
+	name    string
+	pattern string
+}
+
+// operation builds the Operation of a route
+func (g *openAPIGenerator) operation(route *Route, pathParams []pathParam) *Operation {
+	d := route.doc
+	op := &Operation{
+		OperationID: d.operationID,
+		Summary:     d.summary,
+		Description: d.description,
+		Tags:        d.tags,
+		Security:    d.security,
+		Deprecated:  d.deprecated,
+		Responses:   map[string]*Response{},
+	}
+	if op.OperationID == "" {
+		op.OperationID = route.name
+	}
+
+	declared := map[string]bool{}
+	if d.request != nil {
+		params, body := g.requestSchema(d.request)
+		for _, p := range params {
+			declared[p.In+":"+p.Name] = true
+		}
+		op.Parameters = params
+		if body != nil {
+			op.RequestBody = body
+		}
+	}
+	op.Parameters = append(op.Parameters, d.params...)
+	for _, p := range d.params {
+		declared[p.In+":"+p.Name] = true
+	}
+
+	// Path parameters of the pattern are always documented
+	for _, pp := range pathParams {
+		if declared["path:"+pp.name] {
+			continue
+		}
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:     pp.name,
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: SchemaType{"string"}, Pattern: anchoredPattern(pp.pattern)},
+		})
+	}
+	sort.SliceStable(op.Parameters, func(i, j int) bool {
+		return parameterOrder(op.Parameters[i].In) < parameterOrder(op.Parameters[j].In)
+	})
+
+	for _, resp := range d.responses {
+		r := &Response{Description: resp.description}
+		if r.Description == "" {
+			r.Description = http.StatusText(resp.status)
+		}
+		if resp.body != nil {
+			r.Content = map[string]*MediaType{
+				"application/json": {Schema: g.schema(resp.body)},
+			}
+		}
+		op.Responses[strconv.Itoa(resp.status)] = r
+	}
+	if len(op.Responses) == 0 {
+		op.Responses["200"] = &Response{Description: http.StatusText(http.StatusOK)}
+	}
+
+	return op
+}
+
+func anchoredPattern(rx string) string {
+	if rx == "" {
+		return ""
+	}
+
+	return "^(?:" + rx + ")$"
+}
+
+func parameterOrder(in string) int {
+	switch in {
+	case "path":
+		return 0
+	case "query":
+		return 1
+	case "header":
+		return 2
+	}
+
+	return 3
+}
+
+// ServeOpenAPI registers the generated document at cfg.Path+"/openapi.json"
+// and cfg.Path+"/openapi.yaml", and an offline viewer at cfg.Path.
+// The document is generated on the first request, so routes registered
+// after this call are included.
+func (r *Router) ServeOpenAPI(cfg OpenAPIConfig) {
+	base := strings.TrimSuffix(cfg.Path, "/")
+	if base == "" {
+		base = "/docs"
+	}
+
+	var (
+		doc  *OpenAPI
+		once sync.Once
+	)
+	generate := func() *OpenAPI {
+		once.Do(func() {
+			doc = r.OpenAPI(cfg)
+		})
+		return doc
+	}
+
+	r.Get(base+"/openapi.json", func(ctx *Context) {
+		ctx.SendJSON(generate(), http.StatusOK)
+	}).Hidden()
+
+	r.Get(base+"/openapi.yaml", func(ctx *Context) {
+		b, err := json.Marshal(generate())
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		out, err := jsonToYAML(b)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.WriteHeader("Content-Type", "application/yaml")
+		ctx.SendBytes(out, http.StatusOK)
+	}).Hidden()
+
+	r.Get(base, func(ctx *Context) {
+		// The document is linked relative to the viewer, so it is found
+		// under Mount and behind proxies that strip a path prefix. The
+		// request URI is the path the browser resolves against, even if
+		// a middleware such as StripSlashes rewrote URL.Path.
+		p, _, _ := strings.Cut(ctx.Request.RequestURI, "?")
+		if p == "" {
+			p = ctx.Request.URL.EscapedPath()
+		}
+		spec := "openapi.json"
+		if !strings.HasSuffix(p, "/") {
+			spec = path.Base(p) + "/" + spec
+		}
+		ctx.SendHTML(openAPIViewer(cfg.Info.Title, spec), http.StatusOK)
+	}).Hidden()
+}
@@ -0,0 +1,365 @@
+package si
+
+import (
+	"encoding"
+	"encoding/json"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	rawMessageType    = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// durationPattern matches the durations time.ParseDuration accepts
+const durationPattern = `^[-+]?(0|((\d+\.?\d*|\.\d+)(ns|us|µs|μs|ms|s|m|h))+)$`
+
+// componentNameRegexp matches characters not allowed in component names
+var componentNameRegexp = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// openAPIGenerator reflects Go types into schemas, collecting named
+// struct types as components
+type openAPIGenerator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newOpenAPIGenerator() *openAPIGenerator {
+	return &openAPIGenerator{
+		schemas: map[string]*Schema{},
+		names:   map[reflect.Type]string{},
+	}
+}
+
+// schema returns the schema of t. Named structs are referenced from
+// components.
+func (g *openAPIGenerator) schema(t reflect.Type) *Schema {
+	t = indirectType(t)
+
+	switch {
+	case t == nil:
+		return &Schema{}
+	case t == timeType:
+		return &Schema{Type: SchemaType{"string"}, Format: "date-time"}
+	case t == durationType:
+		return &Schema{Type: SchemaType{"integer"}, Format: "int64", Description: "duration in nanoseconds"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return &Schema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: SchemaType{"string"}}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: SchemaType{"boolean"}}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: SchemaType{"integer"}, Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: SchemaType{"integer"}, Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: SchemaType{"number"}, Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: SchemaType{"number"}, Format: "double"}
+	case reflect.String:
+		return &Schema{Type: SchemaType{"string"}}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: SchemaType{"string"}, Format: "byte"}
+		}
+		return &Schema{Type: SchemaType{"array"}, Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: SchemaType{"object"}, AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t, false)
+		}
+		return g.ref(t)
+	}
+
+	return &Schema{}
+}
+
+// paramSchema returns the schema of a value bound from a parameter or
+// form field. Durations are parsed with time.ParseDuration there, so
+// they are strings rather than the nanoseconds of JSON bodies.
+func (g *openAPIGenerator) paramSchema(t reflect.Type) *Schema {
+	t = indirectType(t)
+
+	switch {
+	case t == durationType:
+		return &Schema{Type: SchemaType{"string"}, Pattern: durationPattern, Description: "duration such as 300ms or 1h30m"}
+	case t != nil && t.Kind() == reflect.Slice && indirectType(t.Elem()) == durationType:
+		return &Schema{Type: SchemaType{"array"}, Items: g.paramSchema(t.Elem())}
+	}
+
+	return g.schema(t)
+}
+
+// ref registers a named struct as a component and returns a reference
+func (g *openAPIGenerator) ref(t reflect.Type) *Schema {
+	name, ok := g.names[t]
+	if !ok {
+		name = componentNameRegexp.ReplaceAllString(t.Name(), "_")
+		if _, taken := g.schemas[name]; taken {
+			name = componentNameRegexp.ReplaceAllString(path.Base(t.PkgPath())+"."+t.Name(), "_")
+		}
+		g.names[t] = name
+		// Reserve the name before descending so recursive types terminate
+		g.schemas[name] = &Schema{}
+		*g.schemas[name] = *g.structSchema(t, false)
+	}
+
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// structSchema builds an object schema following encoding/json field
+// rules. With bodyOnly, fields bound from path, query, header, cookie or
+// form without a json tag are left out.
+func (g *openAPIGenerator) structSchema(t reflect.Type, bodyOnly bool) *Schema {
+	s := &Schema{
+		Type:       SchemaType{"object"},
+		Properties: map[string]*Schema{},
+	}
+	g.addFields(s, t, bodyOnly)
+
+	return s
+}
+
+func (g *openAPIGenerator) addFields(s *Schema, t reflect.Type, bodyOnly bool) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, hasTag := sf.Tag.Lookup("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		ft := indirectType(sf.Type)
+		if sf.Anonymous && !hasTag && ft.Kind() == reflect.Struct {
+			g.addFields(s, ft, bodyOnly)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if bodyOnly && !hasTag && hasBindTag(sf) {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		fs := g.schema(sf.Type)
+		applyValidateTag(fs, sf, ft)
+		s.Properties[name] = fs
+		if hasValidateRule(sf, "required") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// requestSchema splits a request type into parameters and a body
+func (g *openAPIGenerator) requestSchema(t reflect.Type) ([]*Parameter, *RequestBody) {
+	if t.Kind() != reflect.Struct {
+		return nil, &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/json": {Schema: g.schema(t)}},
+		}
+	}
+
+	var params []*Parameter
+	form := &Schema{Type: SchemaType{"object"}, Properties: map[string]*Schema{}}
+	g.collectParams(t, &params, form)
+
+	body := g.structSchema(t, true)
+	content := map[string]*MediaType{}
+	if len(body.Properties) > 0 {
+		content["application/json"] = &MediaType{Schema: body}
+	}
+	if len(form.Properties) > 0 {
+		content["application/x-www-form-urlencoded"] = &MediaType{Schema: form}
+	}
+	if len(content) == 0 {
+		return params, nil
+	}
+
+	return params, &RequestBody{
+		Required: len(body.Required) > 0 || len(form.Required) > 0,
+		Content:  content,
+	}
+}
+
+func (g *openAPIGenerator) collectParams(t reflect.Type, params *[]*Parameter, form *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		ft := indirectType(sf.Type)
+		if sf.Anonymous && ft.Kind() == reflect.Struct {
+			g.collectParams(ft, params, form)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		for _, in := range bindSources {
+			name, ok := sf.Tag.Lookup(in)
+			if !ok || name == "" || name == "-" {
+				continue
+			}
+
+			schema := g.paramSchema(sf.Type)
+			// Size rules on a duration are in nanoseconds, which a
+			// string schema can't express
+			if ft != durationType {
+				applyValidateTag(schema, sf, ft)
+			}
+			required := hasValidateRule(sf, "required")
+
+			if in == "form" {
+				form.Properties[name] = schema
+				if required {
+					form.Required = append(form.Required, name)
+				}
+				continue
+			}
+
+			*params = append(*params, &Parameter{
+				Name:     name,
+				In:       in,
+				Required: required || in == "path",
+				Schema:   schema,
+			})
+		}
+	}
+}
+
+// hasBindTag reports whether a field is bound from a request parameter
+func hasBindTag(sf reflect.StructField) bool {
+	for _, source := range bindSources {
+		if _, ok := sf.Tag.Lookup(source); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasValidateRule reports whether the field's validate tag contains rule
+func hasValidateRule(sf reflect.StructField, rule string) bool {
+	for _, r := range strings.Split(sf.Tag.Get("validate"), ",") {
+		if name, _, _ := strings.Cut(strings.TrimSpace(r), "="); name == rule {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyValidateTag translates validate rules into schema constraints
+func applyValidateTag(s *Schema, sf reflect.StructField, t reflect.Type) {
+	if s.Ref != "" {
+		return
+	}
+
+	for _, rule := range strings.Split(sf.Tag.Get("validate"), ",") {
+		rule, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch rule {
+		case "min", "max", "len", "gt", "lt":
+			f, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			applySizeRule(s, t, rule, f)
+		case "oneof":
+			for _, option := range strings.Fields(param) {
+				s.Enum = append(s.Enum, enumValue(t, option))
+			}
+		case "email":
+			s.Format = "email"
+		case "uuid":
+			s.Format = "uuid"
+		case "url":
+			s.Format = "uri"
+		case "alpha":
+			s.Pattern = alphaRegexp.String()
+		case "alphanum":
+			s.Pattern = alphanumRegexp.String()
+		case "numeric":
+			s.Pattern = numericRegexp.String()
+		}
+	}
+}
+
+func applySizeRule(s *Schema, t reflect.Type, rule string, f float64) {
+	n := int(f)
+	switch t.Kind() {
+	case reflect.String:
+		switch rule {
+		case "min", "gt":
+			if rule == "gt" {
+				n++
+			}
+			s.MinLength = &n
+		case "max", "lt":
+			if rule == "lt" {
+				n--
+			}
+			s.MaxLength = &n
+		case "len":
+			s.MinLength, s.MaxLength = &n, &n
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		switch rule {
+		case "min", "gt":
+			if rule == "gt" {
+				n++
+			}
+			s.MinItems = &n
+		case "max", "lt":
+			if rule == "lt" {
+				n--
+			}
+			s.MaxItems = &n
+		case "len":
+			s.MinItems, s.MaxItems = &n, &n
+		}
+	default:
+		switch rule {
+		case "min":
+			s.Minimum = &f
+		case "max":
+			s.Maximum = &f
+		case "gt":
+			s.ExclusiveMinimum = &f
+		case "lt":
+			s.ExclusiveMaximum = &f
+		case "len":
+			s.Minimum, s.Maximum = &f, &f
+		}
+	}
+}
+
+// enumValue converts a oneof option to the JSON type of the field
+func enumValue(t reflect.Type, option string) any {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseInt(option, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(option, 64); err == nil {
+			return f
+		}
+	}
+
+	return option
+}
@@ -0,0 +1,119 @@
+package si
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/revenkroz/si/middleware"
+)
+
+var specURLRegexp = regexp.MustCompile(`var specURL = "([^"]+)"`)
+
+func TestServeOpenAPIViewerLinksDocument(t *testing.T) {
+	tests := []struct {
+		name   string
+		mount  string
+		path   string
+		viewer string
+	}{
+		{"root router", "", "", "/docs"},
+		{"custom path", "", "/reference/", "/reference"},
+		{"mounted", "/api", "", "/api/docs"},
+		{"trailing slash", "/api", "", "/api/docs/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := NewRouter()
+			if tt.viewer[len(tt.viewer)-1] == '/' {
+				root.Use(middleware.StripSlashes)
+			}
+			api := root
+			if tt.mount != "" {
+				api = NewRouter()
+				root.Mount(tt.mount, api)
+			}
+			api.ServeOpenAPI(OpenAPIConfig{Path: tt.path})
+
+			w := httptest.NewRecorder()
+			root.chi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.viewer, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("viewer = %d", w.Code)
+			}
+			m := specURLRegexp.FindStringSubmatch(w.Body.String())
+			if m == nil {
+				t.Fatal("viewer does not link the document")
+			}
+
+			// Resolve the link like a browser would
+			base, _ := url.Parse("http://example.com" + tt.viewer)
+			ref, _ := url.Parse(m[1])
+			spec := base.ResolveReference(ref).Path
+
+			w = httptest.NewRecorder()
+			root.chi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, spec, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("document linked as %q resolves to %s = %d", m[1], spec, w.Code)
+			}
+		})
+	}
+}
+
+func TestOpenAPIDurations(t *testing.T) {
+	type job struct {
+		Timeout time.Duration   `query:"timeout"`
+		Retries []time.Duration `query:"retry" validate:"max=3"`
+		Wait    time.Duration   `json:"wait"`
+	}
+
+	r := NewRouter()
+	r.Post("/jobs", func(ctx *Context) {
+		var in job
+		if err := ctx.Bind(&in); err != nil {
+			ctx.Response.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		ctx.Response.WriteHeader(http.StatusNoContent)
+	}).Request(job{})
+
+	doc := r.OpenAPI(OpenAPIConfig{})
+	op := doc.Paths["/jobs"].Post
+	if s := op.Parameters[0].Schema; !s.Type.Has("string") || s.Pattern == "" {
+		t.Fatalf("duration parameter schema = %+v", s)
+	}
+	if s := op.Parameters[1].Schema; !s.Type.Has("array") || !s.Items.Type.Has("string") || s.MaxItems == nil {
+		t.Fatalf("duration list parameter schema = %+v", s)
+	}
+	// JSON bodies carry nanoseconds
+	if s := op.RequestBody.Content["application/json"].Schema.Properties["wait"]; !s.Type.Has("integer") {
+		t.Fatalf("duration body schema = %+v", s)
+	}
+
+	h := OpenAPIValidator(doc, OpenAPIValidatorOptions{})(r.chi)
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"timeout=5s", http.StatusNoContent},
+		{"timeout=1h30m&retry=100ms&retry=1.5s", http.StatusNoContent},
+		{"timeout=-0.5us", http.StatusNoContent},
+		{"timeout=0", http.StatusNoContent},
+		{"timeout=5000000000", http.StatusBadRequest},
+		{"timeout=5", http.StatusBadRequest},
+		{"retry=soon", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/jobs?"+tt.query, strings.NewReader(`{"wait": 1000}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.query, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,816 @@
+package si
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// LoadOpenAPI reads an OpenAPI 3.0 or 3.1 document in JSON format.
+// YAML documents have to be converted to JSON first.
+func LoadOpenAPI(r io.Reader) (*OpenAPI, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("si: read OpenAPI document: %w", err)
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] != '{' {
+		return nil, errors.New("si: decode OpenAPI document: not a JSON object, only JSON documents are supported")
+	}
+
+	doc := &OpenAPI{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("si: decode OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("si: unsupported OpenAPI version %q", doc.OpenAPI)
+	}
+
+	return doc, nil
+}
+
+// LoadOpenAPIFile reads an OpenAPI document in JSON format from a file
+func LoadOpenAPIFile(path string) (*OpenAPI, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	return LoadOpenAPI(f)
+}
+
+// OpenAPIValidatorOptions configures OpenAPIValidator
+type OpenAPIValidatorOptions struct {
+	// RejectUnknown responds with 404 to requests whose path is not in
+	// the document and 405 to methods it does not describe. By default
+	// they are passed through.
+	RejectUnknown bool
+	// ValidateResponses checks JSON responses against the document.
+	// Responses are buffered, so this is meant for development.
+	ValidateResponses bool
+	// OnResponseError is called for every response that does not match
+	// the document. Defaults to logging a warning.
+	OnResponseError func(r *http.Request, err error)
+}
+
+// OpenAPIValidator returns a middleware that matches requests to the
+// operations of doc and validates path, query, header and cookie
+// parameters and JSON bodies against their schemas. Invalid requests
+// get a 400 Problem Details response listing every violation.
+func OpenAPIValidator(doc *OpenAPI, opts OpenAPIValidatorOptions) Middleware {
+	v := newOpenAPIValidator(doc)
+	if opts.OnResponseError == nil {
+		opts.OnResponseError = func(r *http.Request, err error) {
+			slog.Warn("response does not match OpenAPI document",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"error", err,
+			)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := Si(r, w)
+
+			item, params := v.match(r.URL.EscapedPath())
+			if item == nil {
+				if opts.RejectUnknown {
+					ctx.SendProblem(NewProblem(http.StatusNotFound, ""))
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			op := item.Operation(r.Method)
+			if op == nil {
+				if opts.RejectUnknown {
+					ctx.SendProblem(NewProblem(http.StatusMethodNotAllowed, ""))
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			errs, status := v.validateRequest(ctx, item, op, params)
+			if len(errs) > 0 {
+				ctx.SendProblem(NewProblem(status, "request does not match the API specification").
+					With("errors", errs.Details()))
+				return
+			}
+
+			if !opts.ValidateResponses {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rec := &bodyRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			if err := v.validateResponse(op, rec); err != nil {
+				opts.OnResponseError(r, err)
+			}
+		})
+	}
+}
+
+// bodyRecorder passes a response through while keeping a copy of it
+type bodyRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (br *bodyRecorder) WriteHeader(code int) {
+	if !br.wroteHeader {
+		br.status = code
+		br.wroteHeader = true
+	}
+	br.ResponseWriter.WriteHeader(code)
+}
+
+func (br *bodyRecorder) Write(b []byte) (int, error) {
+	if !br.wroteHeader {
+		br.WriteHeader(http.StatusOK)
+	}
+	br.body.Write(b)
+	return br.ResponseWriter.Write(b)
+}
+
+func (br *bodyRecorder) Flush() {
+	if f, ok := br.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// SpecError is a single violation of an OpenAPI document
+type SpecError struct {
+	// Field locates the value, e.g. "name" or "/items/0/price"
+	Field string
+	// Source is path, query, header, cookie, body or response
+	Source  string
+	Message string
+}
+
+// SpecErrors lists the violations found in a request or response
+type SpecErrors []SpecError
+
+// Error implements the error interface
+func (e SpecErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, se := range e {
+		msgs[i] = se.Source + " " + se.Field + ": " + se.Message
+	}
+
+	return "openapi: " + strings.Join(msgs, "; ")
+}
+
+// Details returns a JSON-friendly description of the violations
+func (e SpecErrors) Details() []Map {
+	details := make([]Map, len(e))
+	for i, se := range e {
+		details[i] = Map{
+			"field":   se.Field,
+			"source":  se.Source,
+			"message": se.Message,
+		}
+	}
+
+	return details
+}
+
+// openAPIValidator holds the compiled path templates of a document
+type openAPIValidator struct {
+	doc      *OpenAPI
+	prefixes []string
+	paths    []compiledPath
+	patterns sync.Map
+}
+
+type compiledPath struct {
+	re     *regexp.Regexp
+	names  []string
+	item   *PathItem
+	static int
+}
+
+var pathParamRegexp = regexp.MustCompile(`\{[^}]+\}`)
+
+func newOpenAPIValidator(doc *OpenAPI) *openAPIValidator {
+	v := &openAPIValidator{doc: doc}
+
+	for _, server := range doc.Servers {
+		if u, err := url.Parse(server.URL); err == nil && strings.Trim(u.Path, "/") != "" {
+			v.prefixes = append(v.prefixes, "/"+strings.Trim(u.EscapedPath(), "/"))
+		}
+	}
+
+	for template, item := range doc.Paths {
+		cp := compiledPath{item: item}
+		expr := "^"
+		last := 0
+		for _, loc := range pathParamRegexp.FindAllStringIndex(template, -1) {
+			expr += regexp.QuoteMeta(template[last:loc[0]]) + "([^/]+)"
+			cp.names = append(cp.names, template[loc[0]+1:loc[1]-1])
+			last = loc[1]
+		}
+		expr += regexp.QuoteMeta(template[last:]) + "$"
+		cp.re = regexp.MustCompile(expr)
+		cp.static = len(pathParamRegexp.ReplaceAllString(template, ""))
+		v.paths = append(v.paths, cp)
+	}
+
+	// Concrete paths take precedence over templated ones
+	sort.Slice(v.paths, func(i, j int) bool {
+		if len(v.paths[i].names) != len(v.paths[j].names) {
+			return len(v.paths[i].names) < len(v.paths[j].names)
+		}
+		return v.paths[i].static > v.paths[j].static
+	})
+
+	return v
+}
+
+// match finds the path item for an escaped request path and extracts
+// its path parameters, unescaping each once
+func (v *openAPIValidator) match(path string) (*PathItem, map[string]string) {
+	candidates := []string{path}
+	for _, prefix := range v.prefixes {
+		if strings.HasPrefix(path, prefix) {
+			candidates = append(candidates, "/"+strings.TrimPrefix(strings.TrimPrefix(path, prefix), "/"))
+		}
+	}
+
+	for _, candidate := range candidates {
+		for _, cp := range v.paths {
+			m := cp.re.FindStringSubmatch(candidate)
+			if m == nil {
+				continue
+			}
+			params := map[string]string{}
+			for i, name := range cp.names {
+				value, err := url.PathUnescape(m[i+1])
+				if err != nil {
+					value = m[i+1]
+				}
+				params[name] = value
+			}
+			return cp.item, params
+		}
+	}
+
+	return nil, nil
+}
+
+// validateRequest checks parameters and body and returns the violations
+// with the status to respond with
+func (v *openAPIValidator) validateRequest(ctx *Context, item *PathItem, op *Operation, pathParams map[string]string) (SpecErrors, int) {
+	var errs SpecErrors
+	r := ctx.Request
+
+	// Operation parameters override path-level ones with the same name
+	params := map[string]*Parameter{}
+	var order []string
+	for _, list := range [][]*Parameter{item.Parameters, op.Parameters} {
+		for _, p := range list {
+			p = v.resolveParameter(p)
+			if p == nil {
+				continue
+			}
+			key := p.In + ":" + p.Name
+			if _, ok := params[key]; !ok {
+				order = append(order, key)
+			}
+			params[key] = p
+		}
+	}
+
+	query := r.URL.Query()
+	for _, key := range order {
+		p := params[key]
+
+		var values []string
+		switch p.In {
+		case "path":
+			if value, ok := pathParams[p.Name]; ok {
+				values = []string{value}
+			}
+		case "query":
+			values = query[p.Name]
+		case "header":
+			values = r.Header.Values(p.Name)
+		case "cookie":
+			if c, err := r.Cookie(p.Name); err == nil {
+				values = []string{c.Value}
+			}
+		}
+
+		if len(values) == 0 {
+			if p.Required || p.In == "path" {
+				errs = append(errs, SpecError{Field: p.Name, Source: p.In, Message: "is required"})
+			}
+			continue
+		}
+		if p.Schema == nil {
+			continue
+		}
+
+		value, err := v.parameterValue(p, values)
+		if err != nil {
+			errs = append(errs, SpecError{Field: p.Name, Source: p.In, Message: err.Error()})
+			continue
+		}
+		v.validateValue(p.Schema, value, p.Name, p.In, &errs)
+	}
+
+	status := http.StatusBadRequest
+	if body := v.resolveRequestBody(op.RequestBody); body != nil {
+		raw, err := ctx.GetRawContent()
+		if err != nil {
+			errs = append(errs, SpecError{Source: "body", Message: err.Error()})
+			return errs, status
+		}
+
+		if len(raw) == 0 {
+			if body.Required {
+				errs = append(errs, SpecError{Source: "body", Message: "is required"})
+			}
+			return errs, status
+		}
+
+		mediaType, media := matchContent(body.Content, ctx.ContentType())
+		if media == nil {
+			errs = append(errs, SpecError{Source: "body", Message: fmt.Sprintf("unsupported content type %q", ctx.ContentType())})
+			return errs, http.StatusUnsupportedMediaType
+		}
+		if media.Schema != nil && isJSONMediaType(mediaType, ctx.ContentType()) {
+			dec := json.NewDecoder(bytes.NewReader(raw))
+			dec.UseNumber()
+			var value any
+			if err := dec.Decode(&value); err != nil {
+				errs = append(errs, SpecError{Source: "body", Message: "invalid JSON: " + err.Error()})
+				return errs, status
+			}
+			v.validateValue(media.Schema, value, "", "body", &errs)
+		}
+	}
+
+	return errs, status
+}
+
+// validateResponse checks a recorded JSON response against the document
+func (v *openAPIValidator) validateResponse(op *Operation, rec *bodyRecorder) error {
+	status := strconv.Itoa(rec.status)
+	resp := op.Responses[status]
+	if resp == nil {
+		resp = op.Responses[status[:1]+"XX"]
+	}
+	if resp == nil {
+		resp = op.Responses["default"]
+	}
+	resp = v.resolveResponse(resp)
+	if resp == nil {
+		return fmt.Errorf("status %d is not documented", rec.status)
+	}
+
+	contentType := rec.Header().Get("Content-Type")
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = strings.TrimSpace(contentType[:i])
+	}
+
+	if len(resp.Content) == 0 || rec.body.Len() == 0 {
+		return nil
+	}
+
+	mediaType, media := matchContent(resp.Content, contentType)
+	if media == nil {
+		return fmt.Errorf("content type %q is not documented for status %d", contentType, rec.status)
+	}
+	if media.Schema == nil || !isJSONMediaType(mediaType, contentType) {
+		return nil
+	}
+
+	dec := json.NewDecoder(&rec.body)
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return fmt.Errorf("invalid JSON response: %w", err)
+	}
+
+	var errs SpecErrors
+	v.validateValue(media.Schema, value, "", "response", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// matchContent finds the content entry for a media type, honouring
+// "type/*" and "*/*" ranges
+func matchContent(content map[string]*MediaType, mediaType string) (string, *MediaType) {
+	if m, ok := content[mediaType]; ok {
+		return mediaType, m
+	}
+	main, _, _ := strings.Cut(mediaType, "/")
+	if m, ok := content[main+"/*"]; ok {
+		return main + "/*", m
+	}
+	if m, ok := content["*/*"]; ok {
+		return "*/*", m
+	}
+
+	return "", nil
+}
+
+func isJSONMediaType(declared, actual string) bool {
+	for _, mt := range []string{declared, actual} {
+		if mt == "application/json" || strings.HasSuffix(mt, "+json") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parameterValue converts raw parameter values to the JSON types of
+// the parameter schema. Arrays accept repeated and comma-separated values.
+func (v *openAPIValidator) parameterValue(p *Parameter, values []string) (any, error) {
+	schema := v.resolveSchema(p.Schema)
+
+	if schema.Type.Has("array") {
+		if len(values) == 1 && (p.In != "query" || (p.Explode != nil && !*p.Explode)) {
+			values = strings.Split(values[0], ",")
+		}
+		items := &Schema{}
+		if schema.Items != nil {
+			items = v.resolveSchema(schema.Items)
+		}
+		list := make([]any, len(values))
+		for i, raw := range values {
+			value, err := scalarValue(items, raw)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = value
+		}
+		return list, nil
+	}
+
+	return scalarValue(schema, values[0])
+}
+
+func scalarValue(schema *Schema, raw string) (any, error) {
+	switch {
+	case schema.Type.Has("integer"), schema.Type.Has("number"):
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			if schema.Type.Has("string") {
+				return raw, nil
+			}
+			return nil, fmt.Errorf("must be a number, got %q", raw)
+		}
+		return json.Number(raw), nil
+	case schema.Type.Has("boolean"):
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("must be a boolean, got %q", raw)
+		}
+		return b, nil
+	}
+
+	return raw, nil
+}
+
+// resolveRef follows a local "#/components/..." reference
+func (v *openAPIValidator) resolveRef(ref string) (kind, name string) {
+	parts := strings.Split(strings.TrimPrefix(ref, "#/components/"), "/")
+	if len(parts) != 2 || !strings.HasPrefix(ref, "#/components/") {
+		return "", ""
+	}
+
+	return parts[0], strings.NewReplacer("~1", "/", "~0", "~").Replace(parts[1])
+}
+
+func (v *openAPIValidator) resolveSchema(s *Schema) *Schema {
+	for i := 0; s != nil && s.Ref != "" && i < 32; i++ {
+		kind, name := v.resolveRef(s.Ref)
+		if kind != "schemas" || v.doc.Components == nil {
+			return &Schema{}
+		}
+		next, ok := v.doc.Components.Schemas[name]
+		if !ok {
+			return &Schema{}
+		}
+		s = next
+	}
+	if s == nil {
+		return &Schema{}
+	}
+
+	return s
+}
+
+func (v *openAPIValidator) resolveParameter(p *Parameter) *Parameter {
+	if p == nil || p.Ref == "" {
+		return p
+	}
+	kind, name := v.resolveRef(p.Ref)
+	if kind != "parameters" || v.doc.Components == nil {
+		return nil
+	}
+
+	return v.doc.Components.Parameters[name]
+}
+
+func (v *openAPIValidator) resolveRequestBody(b *RequestBody) *RequestBody {
+	if b == nil || b.Ref == "" {
+		return b
+	}
+	kind, name := v.resolveRef(b.Ref)
+	if kind != "requestBodies" || v.doc.Components == nil {
+		return nil
+	}
+
+	return v.doc.Components.RequestBodies[name]
+}
+
+func (v *openAPIValidator) resolveResponse(r *Response) *Response {
+	if r == nil || r.Ref == "" {
+		return r
+	}
+	kind, name := v.resolveRef(r.Ref)
+	if kind != "responses" || v.doc.Components == nil {
+		return nil
+	}
+
+	return v.doc.Components.Responses[name]
+}
+
+// validateValue checks a decoded JSON value against schema, appending
+// violations to errs. field is a JSON pointer for body values.
+func (v *openAPIValidator) validateValue(schema *Schema, value any, field, source string, errs *SpecErrors) {
+	s := v.resolveSchema(schema)
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, SpecError{Field: field, Source: source, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, sub := range s.AllOf {
+		v.validateValue(sub, value, field, source, errs)
+	}
+	if len(s.AnyOf) > 0 && v.countMatches(s.AnyOf, value) == 0 {
+		fail("must match at least one schema in anyOf")
+	}
+	if len(s.OneOf) > 0 {
+		if n := v.countMatches(s.OneOf, value); n != 1 {
+			fail("must match exactly one schema in oneOf, matched %d", n)
+		}
+	}
+	if s.Not != nil && v.countMatches([]*Schema{s.Not}, value) == 1 {
+		if len(s.Not.Type) == 0 && s.Not.Ref == "" {
+			fail("is not allowed")
+		} else {
+			fail("must not match the schema in not")
+		}
+	}
+
+	if value == nil {
+		if s.Nullable || s.Type.Has("null") || len(s.Type) == 0 {
+			return
+		}
+		fail("must not be null")
+		return
+	}
+
+	if len(s.Type) > 0 && !matchesType(s.Type, value) {
+		fail("must be of type %s", strings.Join(s.Type, " or "))
+		return
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		fail("must be one of the allowed values")
+	}
+
+	switch t := value.(type) {
+	case string:
+		n := utf8.RuneCountInString(t)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("must be at least %d characters long", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("must be at most %d characters long", *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			if re := v.pattern(s.Pattern); re != nil && !re.MatchString(t) {
+				fail("must match pattern %s", s.Pattern)
+			}
+		}
+		if msg := checkFormat(s.Format, t); msg != "" {
+			fail("%s", msg)
+		}
+	case json.Number:
+		f, _ := t.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("must be >= %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail("must be <= %v", *s.Maximum)
+		}
+		if s.ExclusiveMinimum != nil && f <= *s.ExclusiveMinimum {
+			fail("must be > %v", *s.ExclusiveMinimum)
+		}
+		if s.ExclusiveMaximum != nil && f >= *s.ExclusiveMaximum {
+			fail("must be < %v", *s.ExclusiveMaximum)
+		}
+	case []any:
+		if s.MinItems != nil && len(t) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(t) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.UniqueItems && !uniqueItems(t) {
+			fail("must have unique items")
+		}
+		if s.Items != nil {
+			for i, item := range t {
+				v.validateValue(s.Items, item, childField(field, source, strconv.Itoa(i)), source, errs)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := t[name]; !ok {
+				*errs = append(*errs, SpecError{Field: childField(field, source, name), Source: source, Message: "is required"})
+			}
+		}
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if prop, ok := s.Properties[k]; ok {
+				v.validateValue(prop, t[k], childField(field, source, k), source, errs)
+			} else if s.AdditionalProperties != nil {
+				v.validateValue(s.AdditionalProperties, t[k], childField(field, source, k), source, errs)
+			}
+		}
+	}
+}
+
+// countMatches returns how many schemas value satisfies
+func (v *openAPIValidator) countMatches(schemas []*Schema, value any) int {
+	n := 0
+	for _, sub := range schemas {
+		var errs SpecErrors
+		v.validateValue(sub, value, "", "", &errs)
+		if len(errs) == 0 {
+			n++
+		}
+	}
+
+	return n
+}
+
+// pattern compiles and caches a schema pattern
+func (v *openAPIValidator) pattern(expr string) *regexp.Regexp {
+	if re, ok := v.patterns.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil
+	}
+	v.patterns.Store(expr, re)
+
+	return re
+}
+
+// childField appends a key to a body JSON pointer
+func childField(field, source, key string) string {
+	if source == "body" || source == "response" {
+		return field + "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+	}
+
+	return field + "." + key
+}
+
+func matchesType(types SchemaType, value any) bool {
+	for _, t := range types {
+		switch t {
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := value.(json.Number); ok {
+				return true
+			}
+		case "integer":
+			if n, ok := value.(json.Number); ok {
+				if f, err := n.Float64(); err == nil && f == math.Trunc(f) {
+					return true
+				}
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]any); ok {
+				return true
+			}
+		case "object":
+			if _, ok := value.(map[string]any); ok {
+				return true
+			}
+		case "null":
+			if value == nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func inEnum(enum []any, value any) bool {
+	b, _ := json.Marshal(value)
+	for _, option := range enum {
+		if o, _ := json.Marshal(option); bytes.Equal(o, b) {
+			return true
+		}
+		// Numbers may be spelled differently, e.g. 1 and 1.0
+		if n, ok := value.(json.Number); ok {
+			f, _ := n.Float64()
+			if of, ok := option.(float64); ok && of == f {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func uniqueItems(items []any) bool {
+	seen := map[string]bool{}
+	for _, item := range items {
+		b, _ := json.Marshal(item)
+		if seen[string(b)] {
+			return false
+		}
+		seen[string(b)] = true
+	}
+
+	return true
+}
+
+// checkFormat validates well-known string formats. Unknown formats pass.
+func checkFormat(format, value string) string {
+	var err error
+	switch format {
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, value)
+	case "date":
+		_, err = time.Parse(time.DateOnly, value)
+	case "email":
+		var addr *mail.Address
+		addr, err = mail.ParseAddress(value)
+		if err == nil && addr.Address != value {
+			err = errors.New("display name not allowed")
+		}
+	case "uuid":
+		if !uuidRegexp.MatchString(value) {
+			err = errors.New("invalid uuid")
+		}
+	case "uri":
+		var u *url.URL
+		u, err = url.Parse(value)
+		if err == nil && u.Scheme == "" {
+			err = errors.New("missing scheme")
+		}
+	default:
+		return ""
+	}
+
+	if err != nil {
+		return "must be a valid " + format
+	}
+
+	return ""
+}
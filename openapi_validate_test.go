@@ -0,0 +1,195 @@
+package si
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testSpec = `{
+  "openapi": "3.1.0",
+  "info": {"title": "test", "version": "1"},
+  "servers": [{"url": "https://api.example.com/v1"}],
+  "paths": {
+    "/users/{name}": {
+      "parameters": [
+        {"name": "name", "in": "path", "required": true, "schema": {"type": "string", "pattern": "^[a-z]+$"}}
+      ],
+      "get": {
+        "parameters": [
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 100}},
+          {"name": "X-Tenant", "in": "header", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"description": "OK", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}}
+        }
+      },
+      "put": {
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}
+        },
+        "responses": {"204": {"description": "No Content"}}
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "User": {
+        "type": "object",
+        "required": ["name"],
+        "properties": {
+          "name": {"type": "string", "minLength": 2},
+          "email": {"type": "string", "format": "email"}
+        },
+        "additionalProperties": false
+      }
+    }
+  }
+}`
+
+func testValidator(t *testing.T, opts OpenAPIValidatorOptions, next http.HandlerFunc) http.Handler {
+	t.Helper()
+
+	doc, err := LoadOpenAPI(strings.NewReader(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return OpenAPIValidator(doc, opts)(next)
+}
+
+func TestOpenAPIValidatorRequests(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		header     string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{"valid", "GET", "/users/ann?limit=10", "t1", "", http.StatusNoContent, ""},
+		{"server prefix", "GET", "/v1/users/ann", "t1", "", http.StatusNoContent, ""},
+		{"bad path parameter", "GET", "/users/Ann1", "t1", "", http.StatusBadRequest, "pattern"},
+		{"escaped path parameter", "GET", "/users/%61nn", "t1", "", http.StatusNoContent, ""},
+		// %2541 is "%41" once unescaped, which must not be unescaped again to "A"
+		{"unescaped once", "GET", "/users/%2561nn", "t1", "", http.StatusBadRequest, "pattern"},
+		{"query out of range", "GET", "/users/ann?limit=1000", "t1", "", http.StatusBadRequest, "limit"},
+		{"query not an integer", "GET", "/users/ann?limit=ten", "t1", "", http.StatusBadRequest, "limit"},
+		{"missing header", "GET", "/users/ann", "", "", http.StatusBadRequest, "X-Tenant"},
+		{"valid body", "PUT", "/users/ann", "", `{"name":"ann","email":"ann@example.com"}`, http.StatusNoContent, ""},
+		{"missing body", "PUT", "/users/ann", "", "", http.StatusBadRequest, "is required"},
+		{"invalid body", "PUT", "/users/ann", "", `{"name":"a","email":"nope","age":3}`, http.StatusBadRequest, "/name"},
+		{"malformed body", "PUT", "/users/ann", "", `{"name":`, http.StatusBadRequest, "invalid JSON"},
+		{"unknown path passes", "GET", "/other", "", "", http.StatusNoContent, ""},
+		{"unknown method passes", "DELETE", "/users/ann", "", "", http.StatusNoContent, ""},
+	}
+
+	h := testValidator(t, OpenAPIValidatorOptions{}, ok)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.header != "" {
+				req.Header.Set("X-Tenant", tt.header)
+			}
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantError) {
+				t.Fatalf("body %s does not mention %q", w.Body, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestLoadOpenAPIErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"yaml", "openapi: 3.1.0\ninfo:\n  title: test\n", "only JSON documents are supported"},
+		{"empty", "", "unexpected end of JSON input"},
+		{"syntax", `{"openapi": "3.1.0",}`, "decode OpenAPI document"},
+		{"version", `{"openapi": "2.0"}`, "unsupported OpenAPI version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadOpenAPI(strings.NewReader(tt.doc))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("LoadOpenAPI() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAPIValidatorRejectUnknown(t *testing.T) {
+	h := testValidator(t, OpenAPIValidatorOptions{RejectUnknown: true}, func(w http.ResponseWriter, r *http.Request) {})
+
+	for target, want := range map[string]int{"/other": http.StatusNotFound, "/users/ann": http.StatusMethodNotAllowed} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, target, nil))
+		if w.Code != want {
+			t.Errorf("DELETE %s = %d, want %d", target, w.Code, want)
+		}
+	}
+}
+
+func TestOpenAPIValidatorResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"valid", http.StatusOK, `{"name":"ann"}`, ""},
+		{"invalid", http.StatusOK, `{"name":"ann","age":3}`, "age"},
+		{"undocumented status", http.StatusTeapot, `{}`, "status 418 is not documented"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got error
+			opts := OpenAPIValidatorOptions{
+				ValidateResponses: true,
+				OnResponseError:   func(r *http.Request, err error) { got = err },
+			}
+			h := testValidator(t, opts, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/users/ann", nil)
+			req.Header.Set("X-Tenant", "t1")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Body.String() != tt.body {
+				t.Fatalf("response altered to %s", w.Body)
+			}
+			switch {
+			case tt.wantErr == "" && got != nil:
+				t.Fatalf("unexpected error: %v", got)
+			case tt.wantErr != "" && (got == nil || !strings.Contains(got.Error(), tt.wantErr)):
+				t.Fatalf("error = %v, want one mentioning %q", got, tt.wantErr)
+			}
+			var specErrs SpecErrors
+			if tt.name == "invalid" && !errors.As(got, &specErrs) {
+				t.Fatalf("error %T is not SpecErrors", got)
+			}
+		})
+	}
+}
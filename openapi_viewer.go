@@ -0,0 +1,173 @@
+package si
+
+import (
+	"encoding/json"
+	"html"
+	"strings"
+)
+
+// openAPIViewer returns a self-contained HTML page that renders the
+// document at specURL. It has no external dependencies, so it works
+// offline.
+func openAPIViewer(title, specURL string) string {
+	if title == "" {
+		title = "API"
+	}
+	url, _ := json.Marshal(specURL)
+
+	r := strings.NewReplacer(
+		"{{title}}", html.EscapeString(title),
+		"{{specURL}}", string(url),
+	)
+
+	return r.Replace(openAPIViewerTemplate)
+}
+
+const openAPIViewerTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{title}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; margin: 0; color: #1f2328; background: #f6f8fa; }
+header { background: #24292f; color: #fff; padding: 16px 32px; }
+header h1 { margin: 0; font-size: 22px; }
+header p { margin: 4px 0 0; color: #c9d1d9; }
+main { max-width: 1100px; margin: 0 auto; padding: 24px 32px; }
+h2 { font-size: 18px; border-bottom: 1px solid #d0d7de; padding-bottom: 6px; margin-top: 32px; }
+details { background: #fff; border: 1px solid #d0d7de; border-radius: 6px; margin: 8px 0; }
+summary { cursor: pointer; padding: 10px 12px; display: flex; gap: 12px; align-items: center; }
+.method { font-weight: 700; font-size: 12px; color: #fff; border-radius: 4px; padding: 3px 8px; min-width: 56px; text-align: center; text-transform: uppercase; }
+.get { background: #0969da; } .post { background: #1a7f37; } .put { background: #9a6700; }
+.patch { background: #8250df; } .delete { background: #cf222e; } .head, .options, .trace { background: #57606a; }
+.path { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-weight: 600; }
+.deprecated .path { text-decoration: line-through; }
+.summary { color: #57606a; }
+.body { padding: 0 16px 16px; border-top: 1px solid #d0d7de; }
+table { border-collapse: collapse; width: 100%; font-size: 14px; }
+th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #eaeef2; vertical-align: top; }
+pre { background: #f6f8fa; border: 1px solid #d0d7de; border-radius: 6px; padding: 10px; overflow: auto; font-size: 13px; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; }
+.error { color: #cf222e; }
+</style>
+</head>
+<body>
+<header><h1 id="title">{{title}}</h1><p id="subtitle"></p></header>
+<main id="content">Loading&hellip;</main>
+<script>
+(function () {
+  var specURL = {{specURL}};
+  var content = document.getElementById("content");
+  var methods = ["get", "put", "post", "delete", "options", "head", "patch", "trace"];
+
+  function el(tag, attrs, children) {
+    var node = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (k) { node.setAttribute(k, attrs[k]); });
+    (children || []).forEach(function (c) {
+      node.appendChild(typeof c === "string" ? document.createTextNode(c) : c);
+    });
+    return node;
+  }
+
+  function resolve(spec, schema, depth, seen) {
+    if (!schema || typeof schema !== "object" || depth > 8) return schema;
+    if (schema.$ref) {
+      if (seen[schema.$ref]) return { $ref: schema.$ref };
+      var target = schema.$ref.replace(/^#\//, "").split("/").reduce(function (o, k) { return o && o[k]; }, spec);
+      var next = Object.assign({}, seen); next[schema.$ref] = true;
+      return resolve(spec, target, depth + 1, next);
+    }
+    var out = Array.isArray(schema) ? [] : {};
+    Object.keys(schema).forEach(function (k) { out[k] = resolve(spec, schema[k], depth + 1, seen); });
+    return out;
+  }
+
+  function schemaBlock(spec, schema) {
+    return el("pre", {}, [el("code", {}, [JSON.stringify(resolve(spec, schema, 0, {}), null, 2)])]);
+  }
+
+  function contentBlocks(spec, contentMap) {
+    var nodes = [];
+    Object.keys(contentMap || {}).forEach(function (type) {
+      nodes.push(el("div", {}, [el("code", {}, [type])]));
+      nodes.push(schemaBlock(spec, contentMap[type].schema));
+    });
+    return nodes;
+  }
+
+  function operation(spec, path, method, op) {
+    var body = el("div", { "class": "body" }, []);
+    if (op.description) body.appendChild(el("p", {}, [op.description]));
+
+    if (op.parameters && op.parameters.length) {
+      body.appendChild(el("h4", {}, ["Parameters"]));
+      var rows = op.parameters.map(function (p) {
+        p = resolve(spec, p, 0, {});
+        var type = p.schema ? (p.schema.type || "") + (p.schema.format ? " (" + p.schema.format + ")" : "") : "";
+        return el("tr", {}, [
+          el("td", {}, [el("code", {}, [p.name + (p.required ? " *" : "")])]),
+          el("td", {}, [p.in]),
+          el("td", {}, [String(type)]),
+          el("td", {}, [p.description || ""])
+        ]);
+      });
+      body.appendChild(el("table", {}, [
+        el("tr", {}, [el("th", {}, ["Name"]), el("th", {}, ["In"]), el("th", {}, ["Type"]), el("th", {}, ["Description"])])
+      ].concat(rows)));
+    }
+
+    if (op.requestBody) {
+      var rb = resolve(spec, op.requestBody, 0, {});
+      body.appendChild(el("h4", {}, ["Request body" + (rb.required ? " *" : "")]));
+      contentBlocks(spec, rb.content).forEach(function (n) { body.appendChild(n); });
+    }
+
+    body.appendChild(el("h4", {}, ["Responses"]));
+    Object.keys(op.responses || {}).forEach(function (status) {
+      var resp = resolve(spec, op.responses[status], 0, {});
+      body.appendChild(el("div", {}, [el("strong", {}, [status]), " " + (resp.description || "")]));
+      contentBlocks(spec, resp.content).forEach(function (n) { body.appendChild(n); });
+    });
+
+    return el("details", { "class": op.deprecated ? "deprecated" : "" }, [
+      el("summary", {}, [
+        el("span", { "class": "method " + method }, [method]),
+        el("span", { "class": "path" }, [path]),
+        el("span", { "class": "summary" }, [op.summary || ""])
+      ]),
+      body
+    ]);
+  }
+
+  fetch(specURL).then(function (r) { return r.json(); }).then(function (spec) {
+    var info = spec.info || {};
+    document.getElementById("title").textContent = (info.title || "API") + (info.version ? " " + info.version : "");
+    document.getElementById("subtitle").textContent = info.description || "";
+
+    var groups = {}, order = [];
+    Object.keys(spec.paths || {}).sort().forEach(function (path) {
+      methods.forEach(function (method) {
+        var op = spec.paths[path][method];
+        if (!op) return;
+        (op.tags && op.tags.length ? op.tags : ["default"]).forEach(function (tag) {
+          if (!groups[tag]) { groups[tag] = []; order.push(tag); }
+          groups[tag].push(operation(spec, path, method, op));
+        });
+      });
+    });
+
+    content.textContent = "";
+    order.forEach(function (tag) {
+      content.appendChild(el("h2", {}, [tag]));
+      groups[tag].forEach(function (n) { content.appendChild(n); });
+    });
+  }).catch(function (err) {
+    content.textContent = "";
+    content.appendChild(el("p", { "class": "error" }, ["Failed to load " + specURL + ": " + err]));
+  });
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,248 @@
+package si
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/revenkroz/si/middleware"
+)
+
+// problemNamespace is the XML namespace defined by RFC 9457
+const problemNamespace = "urn:ietf:rfc:7807"
+
+// Problem is an RFC 9457 Problem Details object.
+// It implements error, so handlers can return it directly.
+type Problem struct {
+	// Type is a URI reference identifying the problem type.
+	// Empty means "about:blank".
+	Type string
+	// Title is a short, human-readable summary of the problem type
+	Title string
+	// Status is the HTTP status code
+	Status int
+	// Detail is a human-readable explanation of this occurrence
+	Detail string
+	// Instance is a URI reference identifying this occurrence
+	Instance string
+	// Extensions are additional members serialized next to the standard ones
+	Extensions map[string]any
+}
+
+// NewProblem creates a Problem with the status text as its title
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// With sets an extension member
+func (p *Problem) With(key string, value any) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = map[string]any{}
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+// Error implements the error interface
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Title + ": " + p.Detail
+	}
+
+	return p.Title
+}
+
+// members returns the standard members followed by the extensions.
+// Extensions never override standard members.
+func (p *Problem) members() map[string]any {
+	m := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	if p.Type != "" {
+		m["type"] = p.Type
+	}
+	if p.Title != "" {
+		m["title"] = p.Title
+	}
+	if p.Status != 0 {
+		m["status"] = p.Status
+	}
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+
+	return m
+}
+
+// MarshalJSON flattens extension members into the top-level object
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.members())
+}
+
+// MarshalXML encodes the problem in the RFC 9457 XML format
+func (p *Problem) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	start := xml.StartElement{
+		Name: xml.Name{Local: "problem"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: problemNamespace}},
+	}
+
+	return encodeXMLValue(e, start, p.members())
+}
+
+// encodeXMLValue writes maps as child elements and slices as <i> items,
+// following the conventions of RFC 9457 Appendix B.
+func encodeXMLValue(e *xml.Encoder, start xml.StartElement, v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.IsValid() && (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface) {
+		if rv.IsNil() {
+			return e.EncodeElement("", start)
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		keys := make([]string, 0, rv.Len())
+		for _, k := range rv.MapKeys() {
+			keys = append(keys, fmt.Sprint(k.Interface()))
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			value := rv.MapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()))
+			if err := encodeXMLValue(e, xml.StartElement{Name: xml.Name{Local: k}}, value.Interface()); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return e.EncodeElement(rv.Interface(), start)
+		}
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err := encodeXMLValue(e, xml.StartElement{Name: xml.Name{Local: "i"}}, rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case reflect.Invalid:
+		return e.EncodeElement("", start)
+	}
+
+	return e.EncodeElement(rv.Interface(), start)
+}
+
+// ProblemFromError converts err into a Problem. A Problem in the error
+// chain is returned as is; other errors are converted the same way
+// DefaultErrorHandler interprets them.
+func ProblemFromError(err error) *Problem {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		return problem
+	}
+
+	httpErr := toHTTPError(err)
+	problem = NewProblem(httpErr.Status, httpErr.Message)
+	if httpErr.Code != "" {
+		problem.With("code", httpErr.Code)
+	}
+	if httpErr.Details != nil {
+		problem.With("errors", httpErr.Details)
+	}
+
+	return problem
+}
+
+// ProblemErrorHandler is an ErrorHandler that renders errors as
+// Problem Details
+func ProblemErrorHandler(ctx *Context, err error) {
+	problem := ProblemFromError(err)
+
+	if problem.Status >= http.StatusInternalServerError {
+		slog.Error("handler error",
+			"method", ctx.Request.Method,
+			"path", ctx.Request.URL.Path,
+			"status", problem.Status,
+			"error", err,
+		)
+	}
+
+	ctx.SendProblem(problem)
+}
+
+// SendProblem sends a Problem Details response as application/problem+json,
+// or application/problem+xml when the client asks for XML
+func (ctx *Context) SendProblem(p *Problem) {
+	status := p.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	if ctx.prefersXMLProblem() {
+		ctx.Response.Header().Set("Content-Type", "application/problem+xml")
+		ctx.WriteStatus(status)
+		_, _ = fmt.Fprint(ctx.Response, xml.Header)
+		_ = xml.NewEncoder(ctx.Response).Encode(p)
+		return
+	}
+
+	ctx.Response.Header().Set("Content-Type", "application/problem+json")
+	ctx.WriteStatus(status)
+	_ = json.NewEncoder(ctx.Response).Encode(p)
+}
+
+// prefersXMLProblem reports whether the client prefers XML over JSON
+func (ctx *Context) prefersXMLProblem() bool {
+	switch ctx.Negotiate(
+		"application/problem+json",
+		"application/json",
+		"application/problem+xml",
+		"application/xml",
+	) {
+	case "application/problem+xml", "application/xml":
+		return true
+	}
+
+	return false
+}
+
+// UseProblemDetails makes the router report not found and method not
+// allowed responses, handler errors and recovered panics as
+// Problem Details. It must be called before any route is registered.
+func (r *Router) UseProblemDetails() {
+	r.ErrorHandler = ProblemErrorHandler
+
+	r.chi.Use(problemRecoverer)
+	r.chi.NotFound(r.wrap(func(ctx *Context) {
+		ctx.SendProblem(NewProblem(http.StatusNotFound, ""))
+	}))
+	r.chi.MethodNotAllowed(r.wrap(func(ctx *Context) {
+		ctx.SendProblem(NewProblem(http.StatusMethodNotAllowed, ""))
+	}))
+}
+
+// problemRecoverer recovers from panics like middleware.Recoverer and
+// responds with a 500 Problem Details document.
+var problemRecoverer = middleware.RecovererWithConfig(middleware.RecovererConfig{
+	Render: func(w http.ResponseWriter, r *http.Request, _ any) {
+		Si(r, w).SendProblem(NewProblem(http.StatusInternalServerError, ""))
+	},
+})
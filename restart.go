@@ -0,0 +1,25 @@
+package si
+
+import "time"
+
+// Environment variables used to hand listeners to a restarted process
+const (
+	envListenFDs = "SI_LISTEN_FDS"
+	envReadyFD   = "SI_READY_FD"
+)
+
+// restartReadyTimeout bounds how long the old process waits for the
+// new one to become ready
+const restartReadyTimeout = time.Minute
+
+// WithGracefulRestart makes Run restart the binary without downtime on
+// SIGHUP or SIGUSR2: the current executable is started again with the
+// same arguments and inherits the listening sockets; once it has run
+// its start hooks and is serving, this process shuts down gracefully
+// and Run returns nil. If the new process fails to start, this one
+// keeps serving. Only supported on Unix.
+func WithGracefulRestart() Option {
+	return func(s *Server) {
+		s.gracefulRestart = true
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !unix
+
+package si
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+var restartSignals []os.Signal
+
+func inheritedListeners() ([]net.Listener, error) {
+	return nil, nil
+}
+
+func notifyReady() {}
+
+func (s *Server) restart() error {
+	return errors.New("si: graceful restart is not supported on this platform")
+}
@@ -0,0 +1,145 @@
+//go:build unix
+
+package si
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// restartSignals trigger a graceful restart
+var restartSignals = []os.Signal{syscall.SIGHUP, syscall.SIGUSR2}
+
+// inheritedListeners returns the listeners passed by the parent process
+// on a graceful restart. They follow stdin, stdout and stderr.
+func inheritedListeners() ([]net.Listener, error) {
+	n := inheritedFDs()
+	if n == 0 {
+		return nil, nil
+	}
+	_ = os.Unsetenv(envListenFDs)
+
+	var lns []net.Listener
+	for i := 0; i < n; i++ {
+		f := os.NewFile(uintptr(3+i), "listener")
+		ln, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			closeListeners(lns)
+			return nil, fmt.Errorf("si: inherited listener %d: %w", i, err)
+		}
+		// This process now owns the socket file
+		if ul, ok := ln.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(true)
+		}
+		lns = append(lns, ln)
+	}
+
+	return lns, nil
+}
+
+// inheritedFDs returns the number of listeners passed by the parent
+// process, or 0
+func inheritedFDs() int {
+	n, err := strconv.Atoi(os.Getenv(envListenFDs))
+	if err != nil || n <= 0 {
+		return 0
+	}
+
+	return n
+}
+
+// notifyReady tells the parent process that this one is serving
+func notifyReady() {
+	fd, err := strconv.Atoi(os.Getenv(envReadyFD))
+	if err != nil {
+		return
+	}
+	_ = os.Unsetenv(envReadyFD)
+
+	f := os.NewFile(uintptr(fd), "ready")
+	_, _ = f.Write([]byte{1})
+	_ = f.Close()
+}
+
+// restart starts a new process with the listeners and waits until it
+// is ready
+func (s *Server) restart() error {
+	files := make([]*os.File, 0, len(s.listeners))
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	for _, ln := range s.listeners {
+		filer, ok := ln.(interface{ File() (*os.File, error) })
+		if !ok {
+			return fmt.Errorf("si: listener %s can't be passed to a new process", listenerName(ln))
+		}
+		f, err := filer.File()
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	ready, readyW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = ready.Close() }()
+
+	env := make([]string, 0, len(os.Environ())+2)
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, envListenFDs+"=") && !strings.HasPrefix(kv, envReadyFD+"=") {
+			env = append(env, kv)
+		}
+	}
+	env = append(env,
+		envListenFDs+"="+strconv.Itoa(len(files)),
+		envReadyFD+"="+strconv.Itoa(3+len(files)),
+	)
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = env
+	cmd.ExtraFiles = append(files, readyW)
+
+	err = cmd.Start()
+	_ = readyW.Close()
+	if err != nil {
+		return err
+	}
+
+	_ = ready.SetReadDeadline(time.Now().Add(restartReadyTimeout))
+	if _, err := io.ReadFull(ready, make([]byte, 1)); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		if errors.Is(err, io.EOF) {
+			return errors.New("si: new process exited before it was ready")
+		}
+		return fmt.Errorf("si: new process is not ready: %w", err)
+	}
+
+	// The new process serves the socket files from now on
+	for _, ln := range s.listeners {
+		if ul, ok := ln.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+
+	return nil
+}
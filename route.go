@@ -0,0 +1,301 @@
+package si
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Route is a route registered on a Router
+type Route struct {
+	router  *Router
+	method  string
+	pattern string
+	name    string
+	doc     routeDoc
+}
+
+// routeDoc holds the OpenAPI metadata of a route
+type routeDoc struct {
+	summary     string
+	description string
+	operationID string
+	tags        []string
+	request     reflect.Type
+	responses   []routeResponse
+	params      []*Parameter
+	security    []SecurityRequirement
+	deprecated  bool
+	hidden      bool
+}
+
+type routeResponse struct {
+	status      int
+	description string
+	body        reflect.Type
+}
+
+// Name names the route so its URL can be built with Router.URL.
+// Names must be unique; naming a second route the same panics.
+func (rt *Route) Name(name string) *Route {
+	if existing := rt.router.root().findRoute(name); existing != nil && existing != rt {
+		panic(fmt.Sprintf("si: route name %q is already used by %s %s", name, existing.method, existing.Pattern()))
+	}
+	rt.name = name
+
+	return rt
+}
+
+// Method returns the HTTP method of the route
+func (rt *Route) Method() string {
+	return rt.method
+}
+
+// Pattern returns the full route pattern, including mount prefixes
+func (rt *Route) Pattern() string {
+	return joinPattern(rt.router.fullPrefix(), rt.pattern)
+}
+
+// Summary sets the OpenAPI summary of the route
+func (rt *Route) Summary(summary string) *Route {
+	rt.doc.summary = summary
+	return rt
+}
+
+// Description sets the OpenAPI description of the route
+func (rt *Route) Description(description string) *Route {
+	rt.doc.description = description
+	return rt
+}
+
+// OperationID sets the OpenAPI operation ID. Defaults to the route name.
+func (rt *Route) OperationID(id string) *Route {
+	rt.doc.operationID = id
+	return rt
+}
+
+// Tags adds OpenAPI tags to the route
+func (rt *Route) Tags(tags ...string) *Route {
+	rt.doc.tags = append(rt.doc.tags, tags...)
+	return rt
+}
+
+// Request documents the request type. Parameters are taken from its
+// binding tags and the body schema from the remaining fields; see Bind.
+func (rt *Route) Request(v any) *Route {
+	rt.doc.request = indirectType(reflect.TypeOf(v))
+	return rt
+}
+
+// Response documents a response. v is a value of the body type,
+// or nil for a response without body.
+func (rt *Route) Response(status int, v any, description ...string) *Route {
+	resp := routeResponse{
+		status: status,
+		body:   indirectType(reflect.TypeOf(v)),
+	}
+	if len(description) > 0 {
+		resp.description = description[0]
+	}
+
+	for i := range rt.doc.responses {
+		if rt.doc.responses[i].status == status {
+			rt.doc.responses[i] = resp
+			return rt
+		}
+	}
+	rt.doc.responses = append(rt.doc.responses, resp)
+
+	return rt
+}
+
+// Parameter documents a parameter not covered by the request type
+func (rt *Route) Parameter(p *Parameter) *Route {
+	rt.doc.params = append(rt.doc.params, p)
+	return rt
+}
+
+// Security adds a security requirement to the route
+func (rt *Route) Security(scheme string, scopes ...string) *Route {
+	if scopes == nil {
+		scopes = []string{}
+	}
+	rt.doc.security = append(rt.doc.security, SecurityRequirement{scheme: scopes})
+	return rt
+}
+
+// Deprecated marks the route as deprecated
+func (rt *Route) Deprecated() *Route {
+	rt.doc.deprecated = true
+	return rt
+}
+
+// Hidden excludes the route from generated OpenAPI documents
+func (rt *Route) Hidden() *Route {
+	rt.doc.hidden = true
+	return rt
+}
+
+// indirectType strips pointers from t
+func indirectType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+// root returns the topmost router of the chain
+func (r *Router) root() *Router {
+	for r.parent != nil {
+		r = r.parent
+	}
+
+	return r
+}
+
+// fullPrefix returns the concatenated mount prefixes of the router chain
+func (r *Router) fullPrefix() string {
+	prefix := ""
+	for ; r != nil; r = r.parent {
+		prefix = strings.TrimSuffix(r.prefix, "/") + prefix
+	}
+
+	return prefix
+}
+
+// findRoute looks for a named route in r and its subrouters
+func (r *Router) findRoute(name string) *Route {
+	for _, route := range r.routes {
+		if route.name == name {
+			return route
+		}
+	}
+	for _, child := range r.children {
+		if route := child.findRoute(name); route != nil {
+			return route
+		}
+	}
+
+	return nil
+}
+
+// joinPattern appends pattern to a mount prefix. The root pattern of a
+// mounted router maps to the prefix itself.
+func joinPattern(prefix, pattern string) string {
+	if prefix != "" && pattern == "/" {
+		return prefix
+	}
+
+	return prefix + pattern
+}
+
+// URL builds the path of the named route, substituting the {param}
+// placeholders of its pattern with escaped values from params and
+// appending query. Names are looked up across the whole router tree.
+// Missing and unknown parameters and values not matching a {param:regexp}
+// constraint are reported as errors.
+func (r *Router) URL(name string, params Map, query url.Values) (string, error) {
+	if r == nil {
+		return "", errors.New("si: no router to build URL from")
+	}
+
+	route := r.root().findRoute(name)
+	if route == nil {
+		return "", fmt.Errorf("si: unknown route %q", name)
+	}
+
+	path, err := buildPath(route.Pattern(), params)
+	if err != nil {
+		return "", fmt.Errorf("si: route %q: %w", name, err)
+	}
+
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	return path, nil
+}
+
+// buildPath replaces the placeholders of a chi pattern with params
+func buildPath(pattern string, params Map) (string, error) {
+	used := map[string]bool{}
+	b := &strings.Builder{}
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+
+		switch {
+		case c == '{':
+			// Find the matching brace; regexps may contain braces too
+			depth, end := 0, -1
+			for j := i; j < len(pattern); j++ {
+				if pattern[j] == '{' {
+					depth++
+				} else if pattern[j] == '}' {
+					depth--
+					if depth == 0 {
+						end = j
+						break
+					}
+				}
+			}
+			if end < 0 {
+				return "", fmt.Errorf("unclosed parameter in pattern %q", pattern)
+			}
+
+			key, rx, _ := strings.Cut(pattern[i+1:end], ":")
+			value, ok := params[key]
+			if !ok {
+				return "", fmt.Errorf("missing parameter %q", key)
+			}
+			raw := fmt.Sprint(value)
+			if rx != "" {
+				matched, err := regexp.MatchString("^(?:"+rx+")$", raw)
+				if err != nil {
+					return "", err
+				}
+				if !matched {
+					return "", fmt.Errorf("parameter %q value %q does not match %s", key, raw, rx)
+				}
+			}
+
+			used[key] = true
+			b.WriteString(url.PathEscape(raw))
+			i = end
+		case c == '*' && i == len(pattern)-1:
+			if value, ok := params["*"]; ok {
+				segments := strings.Split(fmt.Sprint(value), "/")
+				for k, segment := range segments {
+					segments[k] = url.PathEscape(segment)
+				}
+				b.WriteString(strings.Join(segments, "/"))
+				used["*"] = true
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	var extra []string
+	for key := range params {
+		if !used[key] {
+			extra = append(extra, key)
+		}
+	}
+	if len(extra) > 0 {
+		sort.Strings(extra)
+		return "", fmt.Errorf("unknown parameters %s", strings.Join(extra, ", "))
+	}
+
+	return b.String(), nil
+}
+
+// URLFor builds the URL of a named route. See Router.URL.
+func (ctx *Context) URLFor(name string, params Map, query url.Values) (string, error) {
+	return ctx.router.URL(name, params, query)
+}
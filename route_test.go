@@ -0,0 +1,120 @@
+package si
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func noop(*Context) {}
+
+func mustPanic(t *testing.T, fn func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Fatal("did not panic")
+		}
+	}()
+	fn()
+}
+
+func TestRouteNamesAreUnique(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(r *Router)
+	}{
+		{"same router", func(r *Router) {
+			r.Get("/a", noop).Name("item")
+			r.Get("/b", noop).Name("item")
+		}},
+		{"inside Route", func(r *Router) {
+			r.Get("/a", noop).Name("item")
+			r.Route("/sub", func(sub *Router) {
+				sub.Get("/b", noop).Name("item")
+			})
+		}},
+		{"nested Route", func(r *Router) {
+			r.Route("/v1", func(v1 *Router) {
+				v1.Get("/a", noop).Name("item")
+				v1.Route("/users", func(users *Router) {
+					users.Get("/b", noop).Name("item")
+				})
+			})
+		}},
+		{"after Route", func(r *Router) {
+			r.Route("/sub", func(sub *Router) {
+				sub.Get("/b", noop).Name("item")
+			})
+			r.Get("/a", noop).Name("item")
+		}},
+		{"group", func(r *Router) {
+			r.Get("/a", noop).Name("item")
+			r.Group(func(g *Router) {
+				g.Get("/b", noop).Name("item")
+			})
+		}},
+		{"mounted router", func(r *Router) {
+			r.Get("/a", noop).Name("item")
+			sub := NewRouter()
+			sub.Get("/b", noop).Name("item")
+			r.Mount("/sub", sub)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mustPanic(t, func() { tt.fn(NewRouter()) })
+		})
+	}
+}
+
+func TestRoutePatternInsideRoute(t *testing.T) {
+	r := NewRouter()
+	var pattern string
+	var url string
+	r.Route("/api", func(api *Router) {
+		api.Route("/users", func(users *Router) {
+			pattern = users.Get("/{id}", noop).Name("user").Pattern()
+			url, _ = users.URL("user", Map{"id": 7}, nil)
+		})
+	})
+
+	if pattern != "/api/users/{id}" || url != "/api/users/7" {
+		t.Fatalf("Pattern() = %q, URL() = %q", pattern, url)
+	}
+}
+
+func TestRouteServesSubrouter(t *testing.T) {
+	r := NewRouter()
+	r.Route("/users", func(users *Router) {
+		users.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Users", "1")
+				next.ServeHTTP(w, r)
+			})
+		})
+		users.Get("/{id}", func(ctx *Context) {
+			ctx.Response.WriteHeader(http.StatusNoContent)
+		})
+		users.NotFound(func(ctx *Context) {
+			ctx.Response.WriteHeader(http.StatusTeapot)
+		})
+	})
+
+	tests := []struct {
+		path    string
+		want    int
+		wantHdr string
+	}{
+		{"/users/1", http.StatusNoContent, "1"},
+		{"/users/1/missing", http.StatusTeapot, "1"},
+		{"/other", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.chi.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.want || w.Header().Get("X-Users") != tt.wantHdr {
+			t.Errorf("%s: %d %q, want %d %q", tt.path, w.Code, w.Header().Get("X-Users"), tt.want, tt.wantHdr)
+		}
+	}
+}
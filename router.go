@@ -10,7 +10,20 @@ import (
 type HandlerFunc Handler
 
 type Router struct {
-	chi *chi.Mux
+	chi chi.Router
+
+	// ErrorHandler handles errors reported by handlers via Context.Error
+	// or returned from HandlerE. When nil, the parent router's handler is
+	// used, falling back to DefaultErrorHandler.
+	ErrorHandler ErrorHandler
+
+	parent     *Router
+	prefix     string
+	children   []*Router
+	routes     []*Route
+	validators map[string]ValidationFunc
+	encoders   []mediaEncoder
+	decoders   []mediaDecoder
 }
 
 func NewRouter() *Router {
@@ -30,80 +43,147 @@ func (r *Router) Use(middleware Middleware) {
 	r.chi.Use(middleware)
 }
 
+// With returns an inline router that applies the given middlewares,
+// in addition to r's, to the routes registered on it
+func (r *Router) With(middlewares ...Middleware) *Router {
+	mws := make([]func(http.Handler) http.Handler, len(middlewares))
+	for i, m := range middlewares {
+		mws[i] = m
+	}
+
+	child := &Router{
+		chi:    r.chi.With(mws...),
+		parent: r,
+	}
+	r.children = append(r.children, child)
+
+	return child
+}
+
+// Group creates an inline router sharing r's pattern space and calls fn
+// with it. Middlewares added with Use inside fn only apply to the group.
+func (r *Router) Group(fn func(g *Router)) *Router {
+	g := r.With()
+	if fn != nil {
+		fn(g)
+	}
+
+	return g
+}
+
+// Route creates a subrouter, calls fn with it and mounts it at pattern
+func (r *Router) Route(pattern string, fn func(sub *Router)) *Router {
+	sub := NewRouter()
+	// Attach sub before fn runs, so route names and patterns registered
+	// in fn see the whole tree
+	r.attach(pattern, sub)
+	if fn != nil {
+		fn(sub)
+	}
+	r.chi.Mount(pattern, sub.chi)
+
+	return sub
+}
+
 func (r *Router) Mount(pattern string, router *Router) {
+	r.attach(pattern, router)
 	r.chi.Mount(pattern, router.chi)
 }
 
+// attach makes router a child of r at the pattern prefix. Route names
+// already used in both trees panic, as Route.Name does.
+func (r *Router) attach(pattern string, router *Router) {
+	root := r.root()
+	for _, route := range router.allRoutes() {
+		if route.name == "" {
+			continue
+		}
+		if existing := root.findRoute(route.name); existing != nil {
+			panic(fmt.Sprintf("si: route name %q is already used by %s %s", route.name, existing.method, existing.Pattern()))
+		}
+	}
+
+	router.parent = r
+	router.prefix = pattern
+	r.children = append(r.children, router)
+}
+
 func (r *Router) Handle(pattern string, handler http.Handler) {
 	r.chi.Handle(pattern, handler)
 }
 
 func (r *Router) NotFound(handler HandlerFunc) {
-	r.chi.NotFound(func(writer http.ResponseWriter, request *http.Request) {
-		ctx := Si(request, writer)
-		handler(ctx)
-	})
+	r.chi.NotFound(r.wrap(handler))
 }
 
-func (r *Router) Connect(pattern string, handler HandlerFunc) {
-	r.chi.Connect(pattern, func(writer http.ResponseWriter, request *http.Request) {
-		ctx := Si(request, writer)
-		handler(ctx)
-	})
+func (r *Router) Connect(pattern string, handler HandlerFunc) *Route {
+	return r.addRoute(http.MethodConnect, pattern, handler)
 }
 
-func (r *Router) Delete(pattern string, handler HandlerFunc) {
-	r.chi.Delete(pattern, func(writer http.ResponseWriter, request *http.Request) {
-		ctx := Si(request, writer)
-		handler(ctx)
-	})
+func (r *Router) Delete(pattern string, handler HandlerFunc) *Route {
+	return r.addRoute(http.MethodDelete, pattern, handler)
 }
 
-func (r *Router) Get(pattern string, handler HandlerFunc) {
-	r.chi.Get(pattern, func(writer http.ResponseWriter, request *http.Request) {
-		ctx := Si(request, writer)
-		handler(ctx)
-	})
+func (r *Router) Get(pattern string, handler HandlerFunc) *Route {
+	return r.addRoute(http.MethodGet, pattern, handler)
 }
 
-func (r *Router) Head(pattern string, handler HandlerFunc) {
-	r.chi.Head(pattern, func(writer http.ResponseWriter, request *http.Request) {
-		ctx := Si(request, writer)
-		handler(ctx)
-	})
+func (r *Router) Head(pattern string, handler HandlerFunc) *Route {
+	return r.addRoute(http.MethodHead, pattern, handler)
 }
 
-func (r *Router) Options(pattern string, handler HandlerFunc) {
-	r.chi.Options(pattern, func(writer http.ResponseWriter, request *http.Request) {
-		ctx := Si(request, writer)
-		handler(ctx)
-	})
+func (r *Router) Options(pattern string, handler HandlerFunc) *Route {
+	return r.addRoute(http.MethodOptions, pattern, handler)
 }
 
-func (r *Router) Patch(pattern string, handler HandlerFunc) {
-	r.chi.Patch(pattern, func(writer http.ResponseWriter, request *http.Request) {
-		ctx := Si(request, writer)
-		handler(ctx)
-	})
+func (r *Router) Patch(pattern string, handler HandlerFunc) *Route {
+	return r.addRoute(http.MethodPatch, pattern, handler)
 }
 
-func (r *Router) Post(pattern string, handler HandlerFunc) {
-	r.chi.Post(pattern, func(writer http.ResponseWriter, request *http.Request) {
-		ctx := Si(request, writer)
-		handler(ctx)
-	})
+func (r *Router) Post(pattern string, handler HandlerFunc) *Route {
+	return r.addRoute(http.MethodPost, pattern, handler)
 }
 
-func (r *Router) Put(pattern string, handler HandlerFunc) {
-	r.chi.Put(pattern, func(writer http.ResponseWriter, request *http.Request) {
-		ctx := Si(request, writer)
-		handler(ctx)
-	})
+func (r *Router) Put(pattern string, handler HandlerFunc) *Route {
+	return r.addRoute(http.MethodPut, pattern, handler)
+}
+
+func (r *Router) Trace(pattern string, handler HandlerFunc) *Route {
+	return r.addRoute(http.MethodTrace, pattern, handler)
 }
 
-func (r *Router) Trace(pattern string, handler HandlerFunc) {
-	r.chi.Trace(pattern, func(writer http.ResponseWriter, request *http.Request) {
+// addRoute registers handler and records the route for naming
+func (r *Router) addRoute(method, pattern string, handler HandlerFunc) *Route {
+	r.chi.MethodFunc(method, pattern, r.wrap(handler))
+
+	route := &Route{
+		router:  r,
+		method:  method,
+		pattern: pattern,
+	}
+	route.typedDoc(handler)
+	r.routes = append(r.routes, route)
+
+	return route
+}
+
+// wrap adapts a HandlerFunc to http.HandlerFunc, binding the created
+// context to this router.
+func (r *Router) wrap(handler HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
 		ctx := Si(request, writer)
+		ctx.router = r
 		handler(ctx)
-	})
+	}
+}
+
+// errorHandler returns the closest ErrorHandler in the router chain.
+func (r *Router) errorHandler() ErrorHandler {
+	for ; r != nil; r = r.parent {
+		if r.ErrorHandler != nil {
+			return r.ErrorHandler
+		}
+	}
+
+	return DefaultErrorHandler
 }
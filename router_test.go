@@ -0,0 +1,131 @@
+package si
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// tag is a middleware appending name to the X-Chain response header
+func tag(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Chain", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// chain requests path and returns the status and the middleware chain
+func chain(h http.Handler, path string) (int, string) {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	return w.Code, strings.Join(w.Header().Values("X-Chain"), ",")
+}
+
+func ok(ctx *Context) {
+	ctx.Response.WriteHeader(http.StatusOK)
+}
+
+func TestGroupMiddlewareScope(t *testing.T) {
+	r := NewRouter()
+	r.Use(tag("root"))
+	r.Get("/before", ok)
+	r.Group(func(g *Router) {
+		g.Use(tag("group"))
+		g.Get("/group", ok)
+		g.With(tag("with")).Get("/group/with", ok)
+		g.Group(func(inner *Router) {
+			inner.Use(tag("inner"))
+			inner.Get("/group/inner", ok)
+		})
+		g.Get("/group/after", ok)
+	})
+	r.With(tag("with")).Get("/with", ok)
+	r.Get("/after", ok)
+	r.Route("/sub", func(sub *Router) {
+		sub.Use(tag("sub"))
+		sub.Group(func(g *Router) {
+			g.Use(tag("subgroup"))
+			g.Get("/group", ok)
+		})
+		sub.Get("/plain", ok)
+	})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/before", "root"},
+		{"/group", "root,group"},
+		{"/group/with", "root,group,with"},
+		{"/group/inner", "root,group,inner"},
+		{"/group/after", "root,group"},
+		{"/with", "root,with"},
+		{"/after", "root"},
+		{"/sub/group", "root,sub,subgroup"},
+		{"/sub/plain", "root,sub"},
+	}
+
+	for _, tt := range tests {
+		code, got := chain(r.chi, tt.path)
+		if code != http.StatusOK || got != tt.want {
+			t.Errorf("%s: %d with middlewares %q, want %q", tt.path, code, got, tt.want)
+		}
+	}
+}
+
+func TestGroupRoutePatterns(t *testing.T) {
+	r := NewRouter()
+	var patterns []string
+	r.Route("/api", func(api *Router) {
+		api.Group(func(g *Router) {
+			patterns = append(patterns, g.Get("/users", ok).Pattern())
+			patterns = append(patterns, g.With(tag("x")).Get("/orgs", ok).Pattern())
+		})
+	})
+
+	if strings.Join(patterns, " ") != "/api/users /api/orgs" {
+		t.Fatalf("patterns = %v", patterns)
+	}
+}
+
+func TestServerGroup(t *testing.T) {
+	s := New()
+	s.Use(tag("server"))
+	s.Group(func(g *Router) {
+		g.Use(tag("auth"))
+		g.Get("/account", ok)
+	})
+	s.With(tag("with")).Get("/with", ok)
+	s.Get("/public", ok)
+	s.Route("/api", func(api *Router) {
+		api.Group(func(g *Router) {
+			g.Use(tag("api"))
+			g.Get("/users", ok)
+		})
+	})
+
+	tests := []struct {
+		path     string
+		wantCode int
+		want     string
+	}{
+		{"/account", http.StatusOK, "server,auth"},
+		{"/with", http.StatusOK, "server,with"},
+		{"/public", http.StatusOK, "server"},
+		{"/api/users", http.StatusOK, "server,api"},
+		// Groups share the parent's pattern space; the prefix only
+		// comes from Route
+		{"/users", http.StatusNotFound, "server"},
+	}
+
+	for _, tt := range tests {
+		code, got := chain(s.server.Handler, tt.path)
+		if code != tt.wantCode || got != tt.want {
+			t.Errorf("%s: %d with middlewares %q, want %d %q", tt.path, code, got, tt.wantCode, tt.want)
+		}
+	}
+}
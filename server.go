@@ -2,62 +2,270 @@ package si
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 )
 
+// DefaultShutdownTimeout is how long Stop and Run wait for in-flight
+// requests when Server.ShutdownTimeout is not set
+const DefaultShutdownTimeout = 30 * time.Second
+
+// Hook is a function run when the server starts or shuts down
+type Hook func(ctx context.Context) error
+
+// serverClosingKey is the context key of the server's closing context
+const serverClosingKey ContextKey = "si.server.closing"
+
 // Server is a wrapper around http.Server
 type Server struct {
 	server *http.Server
 	Router *Router
+
+	// ShutdownTimeout bounds how long Stop waits for in-flight requests
+	// before closing the remaining connections, and how long shutdown
+	// hooks may take. Defaults to DefaultShutdownTimeout.
+	ShutdownTimeout time.Duration
+
+	onStart    []Hook
+	onShutdown []Hook
+	// baseContext is the user's http.Server.BaseContext
+	baseContext func(net.Listener) context.Context
+	tls         *TLSConfig
+	// listenFuncs open the listeners; Addr is used when there are none
+	listenFuncs     []listenFunc
+	listeners       []net.Listener
+	gracefulRestart bool
+	h2c             bool
+	// beforeShutdown run before connections are drained
+	beforeShutdown []func()
+	stopOnce       sync.Once
+	stopErr        error
 }
 
-// CreateServer creates a new server
+// CreateServer creates a new server listening on listenAddress.
+// It is a shorthand for New(WithAddr(listenAddress), WithMiddleware(middlewares...)).
 func CreateServer(
 	listenAddress string,
 	middlewares []Middleware,
 ) *Server {
+	return New(WithAddr(listenAddress), WithMiddleware(middlewares...))
+}
+
+// New creates a new server configured by opts. Settings that are not
+// given keep the defaults of DefaultConfig.
+func New(opts ...Option) *Server {
 	r := NewRouter()
-	for _, m := range middlewares {
-		r.Use(m)
-	}
+	defaults := DefaultConfig()
 
-	return &Server{
+	closing, cancelClosing := context.WithCancel(context.Background())
+	s := &Server{
 		server: &http.Server{
-			Addr:    listenAddress,
 			Handler: r.chi,
 		},
 		Router: r,
 	}
+	s.server.BaseContext = func(ln net.Listener) context.Context {
+		base := context.Background()
+		if s.baseContext != nil {
+			base = s.baseContext(ln)
+		}
+		return context.WithValue(base, serverClosingKey, closing)
+	}
+	// Long-lived handlers such as SSE streams are told to finish as
+	// soon as shutdown begins, so they don't hold up draining.
+	s.server.RegisterOnShutdown(cancelClosing)
+
+	WithConfig(defaults)(s)
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// OnStart registers a hook run after the listener is open and before
+// requests are served. Hooks run in registration order; if one fails,
+// the server doesn't start and the shutdown hooks run.
+func (s *Server) OnStart(hook Hook) {
+	s.onStart = append(s.onStart, hook)
 }
 
-// Start starts the server
+// OnShutdown registers a hook run after in-flight requests have been
+// drained, e.g. to close database pools or flush queues. Hooks run in
+// registration order and all of them run even if one fails.
+func (s *Server) OnShutdown(hook Hook) {
+	s.onShutdown = append(s.onShutdown, hook)
+}
+
+// Start starts the server and blocks until it is stopped. After Stop,
+// it returns once the shutdown has finished, with the shutdown's error.
 func (s *Server) Start() error {
-	addr := s.server.Addr
+	lns, err := s.listen(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if err := s.serve(lns); !errors.Is(err, http.ErrServerClosed) {
+		return errors.Join(err, s.Stop())
+	}
+
+	// Stopped by a call to Stop; wait for it to drain and run the hooks
+	return s.Stop()
+}
 
-	ln, err := net.Listen("tcp", addr)
+// Run starts the server and blocks until ctx is cancelled or the
+// process receives SIGINT or SIGTERM, then shuts down gracefully.
+// Errors from serving, draining and hooks are returned; Run never
+// exits the process.
+func (s *Server) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	lns, err := s.listen(ctx)
 	if err != nil {
 		return err
 	}
 
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.serve(lns)
+	}()
+
+	var restart chan os.Signal
+	if s.gracefulRestart && len(restartSignals) > 0 {
+		restart = make(chan os.Signal, 1)
+		signal.Notify(restart, restartSignals...)
+		defer signal.Stop(restart)
+	}
+
+	for {
+		select {
+		case err := <-serveErr:
+			if errors.Is(err, http.ErrServerClosed) {
+				// Stopped by a call to Stop; wait for it to drain and
+				// run the hooks
+				return s.Stop()
+			}
+			return errors.Join(err, s.Stop())
+		case <-restart:
+			log.Println("restarting server")
+			if err := s.restart(); err != nil {
+				log.Println("restart failed, continuing to serve:", err)
+				continue
+			}
+			return s.Stop()
+		case <-ctx.Done():
+			// A second signal kills the process as usual
+			stop()
+			return s.Stop()
+		}
+	}
+}
+
+// listen opens the listeners and runs the start hooks
+func (s *Server) listen(ctx context.Context) ([]net.Listener, error) {
+	if s.tls != nil {
+		cfg, err := s.tls.build()
+		if err != nil {
+			return nil, err
+		}
+		s.server.TLSConfig = cfg
+	}
+
+	lns, err := s.openListeners()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hook := range s.onStart {
+		if err := hook(ctx); err != nil {
+			closeListeners(lns)
+			return nil, errors.Join(fmt.Errorf("si: start hook: %w", err), s.Stop())
+		}
+	}
+	s.listeners = lns
+	notifyReady()
+
 	fmt.Println("-------------------")
-	fmt.Println("Server listening on", addr)
+	for _, ln := range lns {
+		fmt.Println("Server listening on", listenerName(ln))
+	}
 	fmt.Println("-------------------")
 
-	if err := s.server.Serve(ln); err != nil {
-		log.Fatal(err)
+	return lns, nil
+}
+
+// serve serves connections on every listener, with TLS when
+// configured, and returns when one of them stops
+func (s *Server) serve(lns []net.Listener) error {
+	serveErr := make(chan error, len(lns))
+	for _, ln := range lns {
+		go func() {
+			// net/http fills in TLSConfig when setting up HTTP/2, so
+			// it can't tell whether TLS was configured
+			if s.tls != nil {
+				serveErr <- s.server.ServeTLS(ln, "", "")
+				return
+			}
+			serveErr <- s.server.Serve(ln)
+		}()
 	}
 
-	return nil
+	return <-serveErr
 }
 
-// Stop stops the server
+// Stop stops accepting connections, cancels long-lived streams, waits
+// for in-flight requests up to ShutdownTimeout and runs the shutdown
+// hooks. Calling Stop again returns the result of the first call.
 func (s *Server) Stop() error {
+	s.stopOnce.Do(func() {
+		s.stopErr = s.shutdown()
+	})
+
+	return s.stopErr
+}
+
+func (s *Server) shutdown() error {
+	timeout := s.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+
 	log.Println("gracefully shutting down server")
-	if err := s.server.Shutdown(context.Background()); err != nil {
+	for _, fn := range s.beforeShutdown {
+		fn()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := s.server.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Println("shutdown timeout exceeded, closing remaining connections")
+		err = errors.Join(err, s.server.Close())
+	}
+	if err != nil {
 		log.Println("error occurred while gracefully shutting down server")
+	}
+
+	hookCtx, cancelHooks := context.WithTimeout(context.Background(), timeout)
+	defer cancelHooks()
+	for _, hook := range s.onShutdown {
+		if hookErr := hook(hookCtx); hookErr != nil {
+			err = errors.Join(err, fmt.Errorf("si: shutdown hook: %w", hookErr))
+		}
+	}
+
+	if err != nil {
 		return err
 	}
 
@@ -66,43 +274,80 @@ func (s *Server) Stop() error {
 	return nil
 }
 
+// UseProblemDetails makes the server respond with Problem Details.
+// See Router.UseProblemDetails.
+func (s *Server) UseProblemDetails() {
+	s.Router.UseProblemDetails()
+}
+
 // AddRoute adds a subrouter to the server
 func (s *Server) AddRoute(pattern string, subrouter *Router) {
 	s.Router.Mount(pattern, subrouter)
 }
 
-func (s *Server) Get(pattern string, handler HandlerFunc) {
-	s.Router.Get(pattern, handler)
+// URL builds the path of a named route. See Router.URL.
+func (s *Server) URL(name string, params Map, query url.Values) (string, error) {
+	return s.Router.URL(name, params, query)
+}
+
+// Use adds a middleware to the server's router
+func (s *Server) Use(middleware Middleware) {
+	s.Router.Use(middleware)
+}
+
+// With returns an inline router with additional middlewares.
+// See Router.With.
+func (s *Server) With(middlewares ...Middleware) *Router {
+	return s.Router.With(middlewares...)
+}
+
+// Group creates an inline route group. See Router.Group.
+func (s *Server) Group(fn func(g *Router)) *Router {
+	return s.Router.Group(fn)
+}
+
+// Route mounts a subrouter built by fn at pattern. See Router.Route.
+func (s *Server) Route(pattern string, fn func(sub *Router)) *Router {
+	return s.Router.Route(pattern, fn)
+}
+
+// Handle registers an http.Handler for all methods. See Router.Handle.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.Router.Handle(pattern, handler)
+}
+
+func (s *Server) Get(pattern string, handler HandlerFunc) *Route {
+	return s.Router.Get(pattern, handler)
 }
 
-func (s *Server) Post(pattern string, handler HandlerFunc) {
-	s.Router.Post(pattern, handler)
+func (s *Server) Post(pattern string, handler HandlerFunc) *Route {
+	return s.Router.Post(pattern, handler)
 }
 
-func (s *Server) Put(pattern string, handler HandlerFunc) {
-	s.Router.Put(pattern, handler)
+func (s *Server) Put(pattern string, handler HandlerFunc) *Route {
+	return s.Router.Put(pattern, handler)
 }
 
-func (s *Server) Patch(pattern string, handler HandlerFunc) {
-	s.Router.Patch(pattern, handler)
+func (s *Server) Patch(pattern string, handler HandlerFunc) *Route {
+	return s.Router.Patch(pattern, handler)
 }
 
-func (s *Server) Delete(pattern string, handler HandlerFunc) {
-	s.Router.Delete(pattern, handler)
+func (s *Server) Delete(pattern string, handler HandlerFunc) *Route {
+	return s.Router.Delete(pattern, handler)
 }
 
-func (s *Server) Connect(pattern string, handler HandlerFunc) {
-	s.Router.Connect(pattern, handler)
+func (s *Server) Connect(pattern string, handler HandlerFunc) *Route {
+	return s.Router.Connect(pattern, handler)
 }
 
-func (s *Server) Head(pattern string, handler HandlerFunc) {
-	s.Router.Head(pattern, handler)
+func (s *Server) Head(pattern string, handler HandlerFunc) *Route {
+	return s.Router.Head(pattern, handler)
 }
 
-func (s *Server) Options(pattern string, handler HandlerFunc) {
-	s.Router.Options(pattern, handler)
+func (s *Server) Options(pattern string, handler HandlerFunc) *Route {
+	return s.Router.Options(pattern, handler)
 }
 
-func (s *Server) Trace(pattern string, handler HandlerFunc) {
-	s.Router.Trace(pattern, handler)
+func (s *Server) Trace(pattern string, handler HandlerFunc) *Route {
+	return s.Router.Trace(pattern, handler)
 }
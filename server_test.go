@@ -0,0 +1,191 @@
+package si
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+func TestStartWaitsForShutdownStartedByStop(t *testing.T) {
+	s := New(WithAddr("127.0.0.1:0"))
+	hookErr := errors.New("hook failed")
+	hookDone := make(chan struct{})
+	s.OnShutdown(func(context.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		close(hookDone)
+		return hookErr
+	})
+
+	started := make(chan struct{})
+	s.OnStart(func(context.Context) error {
+		close(started)
+		return nil
+	})
+
+	go func() {
+		<-started
+		_ = s.Stop()
+	}()
+
+	err := s.Start()
+	select {
+	case <-hookDone:
+	default:
+		t.Fatal("Start returned before the shutdown hooks ran")
+	}
+	if !errors.Is(err, hookErr) {
+		t.Fatalf("Start() = %v, want the shutdown hook error", err)
+	}
+}
+
+func TestRunWaitsForShutdownStartedByStop(t *testing.T) {
+	s := New(WithAddr("127.0.0.1:0"))
+	hookErr := errors.New("hook failed")
+	s.OnShutdown(func(context.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		return hookErr
+	})
+
+	started := make(chan struct{})
+	s.OnStart(func(context.Context) error {
+		close(started)
+		return nil
+	})
+
+	go func() {
+		<-started
+		_ = s.Stop()
+	}()
+
+	if err := s.Run(context.Background()); !errors.Is(err, hookErr) {
+		t.Fatalf("Run() = %v, want the shutdown hook error", err)
+	}
+}
+
+// startServer starts a server with opts on a random port and returns
+// it with its address
+func startServer(t *testing.T, opts ...Option) (*Server, string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(append(opts, WithListener(ln))...)
+	s.Get("/", func(ctx *Context) {
+		ctx.SendString(ctx.Request.Proto, http.StatusOK)
+	})
+	go func() { _ = s.Start() }()
+	t.Cleanup(func() { _ = s.Stop() })
+
+	return s, ln.Addr().String()
+}
+
+func TestNewDefaults(t *testing.T) {
+	srv := New().server
+	if srv.ReadHeaderTimeout != 5*time.Second || srv.IdleTimeout != 2*time.Minute {
+		t.Fatalf("ReadHeaderTimeout = %v, IdleTimeout = %v", srv.ReadHeaderTimeout, srv.IdleTimeout)
+	}
+	// Read and write timeouts would cut off slow uploads and streams
+	if srv.ReadTimeout != 0 || srv.WriteTimeout != 0 {
+		t.Fatalf("ReadTimeout = %v, WriteTimeout = %v, want both disabled", srv.ReadTimeout, srv.WriteTimeout)
+	}
+
+	srv = New(WithTimeouts(Timeouts{Read: time.Minute})).server
+	if srv.ReadTimeout != time.Minute || srv.ReadHeaderTimeout != 5*time.Second {
+		t.Fatalf("ReadTimeout = %v, ReadHeaderTimeout = %v", srv.ReadTimeout, srv.ReadHeaderTimeout)
+	}
+}
+
+func TestH2CPriorKnowledge(t *testing.T) {
+	_, addr := startServer(t, WithH2C())
+
+	protocols := &http.Protocols{}
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+
+	resp, err := client.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.ProtoMajor != 2 || string(body) != "HTTP/2.0" {
+		t.Fatalf("got %s with body %q, want HTTP/2", resp.Proto, body)
+	}
+}
+
+func TestH2CUpgrade(t *testing.T) {
+	_, addr := startServer(t, WithH2C())
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// HTTP2-Settings is an empty SETTINGS payload
+	_, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: "+addr+"\r\n"+
+		"Connection: Upgrade, HTTP2-Settings\r\nUpgrade: h2c\r\nHTTP2-Settings: \r\n\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("upgrade answered with %s", resp.Status)
+	}
+
+	if _, err := io.WriteString(conn, http2.ClientPreface); err != nil {
+		t.Fatal(err)
+	}
+	framer := http2.NewFramer(conn, br)
+	if err := framer.WriteSettings(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The upgraded request is answered on stream 1
+	var status, body string
+	decoder := hpack.NewDecoder(4096, func(f hpack.HeaderField) {
+		if f.Name == ":status" {
+			status = f.Value
+		}
+	})
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch f := frame.(type) {
+		case *http2.HeadersFrame:
+			if _, err := decoder.Write(f.HeaderBlockFragment()); err != nil {
+				t.Fatal(err)
+			}
+		case *http2.DataFrame:
+			body += string(f.Data())
+		}
+		if frame.Header().StreamID == 1 && frame.Header().Flags.Has(http2.FlagDataEndStream) {
+			break
+		}
+	}
+
+	// The upgraded request keeps its HTTP/1.1 Proto, but the response
+	// came in HTTP/2 frames
+	if status != "200" || body == "" {
+		t.Fatalf("got status %q with body %q, want a 200 response", status, body)
+	}
+}
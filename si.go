@@ -6,6 +6,13 @@ type Map map[string]interface{}
 
 type Handler func(ctx *Context)
 
+// HandlerE is a handler that reports failures by returning an error.
+// Use E to register it on a Router.
+type HandlerE func(ctx *Context) error
+
+// ErrorHandler turns an error returned from a handler into a response
+type ErrorHandler func(ctx *Context, err error)
+
 type Middleware func(http.Handler) http.Handler
 
 // Si creates a new context
@@ -29,3 +36,13 @@ func MW(f Handler) Middleware {
 		})
 	}
 }
+
+// E adapts an error-returning handler to a HandlerFunc.
+// A non-nil error is passed to the router's ErrorHandler.
+func E(f HandlerE) HandlerFunc {
+	return func(ctx *Context) {
+		if err := f(ctx); err != nil {
+			ctx.Error(err)
+		}
+	}
+}
@@ -1,16 +1,19 @@
 package si
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
+
+	"github.com/revenkroz/si/middleware"
 )
 
 // SSEWriter writes Server-Sent Events to the client.
 type SSEWriter struct {
-	w http.ResponseWriter
-	f http.Flusher
+	w  http.ResponseWriter
+	rc *http.ResponseController
 }
 
 // Event sends a named event with data.
@@ -28,8 +31,7 @@ func (s *SSEWriter) Event(event string, data string) error {
 	if _, err := fmt.Fprint(s.w, "\n"); err != nil {
 		return err
 	}
-	s.f.Flush()
-	return nil
+	return s.rc.Flush()
 }
 
 // Data sends an unnamed event with data.
@@ -53,8 +55,7 @@ func (s *SSEWriter) ID(id string) error {
 	if err != nil {
 		return err
 	}
-	s.f.Flush()
-	return nil
+	return s.rc.Flush()
 }
 
 // Retry tells the client to wait the given number of milliseconds
@@ -64,8 +65,7 @@ func (s *SSEWriter) Retry(ms int) error {
 	if err != nil {
 		return err
 	}
-	s.f.Flush()
-	return nil
+	return s.rc.Flush()
 }
 
 // Comment sends an SSE comment (line starting with ":").
@@ -75,28 +75,56 @@ func (s *SSEWriter) Comment(text string) error {
 	if err != nil {
 		return err
 	}
-	s.f.Flush()
-	return nil
+	return s.rc.Flush()
 }
 
 // SSE sets up an SSE connection and calls fn with an SSEWriter.
 // The caller should use ctx.Request.Context().Done() to detect
-// client disconnection inside fn.
+// client disconnection inside fn. The context is also cancelled when
+// the server starts shutting down.
 func (ctx *Context) SSE(fn func(w *SSEWriter)) {
-	flusher, ok := ctx.Response.(http.Flusher)
-	if !ok {
+	if !canFlush(ctx.Response) {
 		http.Error(ctx.Response, "streaming not supported", http.StatusInternalServerError)
 		return
 	}
+	rc := http.NewResponseController(ctx.Response)
 
 	ctx.Response.Header().Set("Content-Type", "text/event-stream")
 	ctx.Response.Header().Set("Cache-Control", "no-cache")
-	ctx.Response.Header().Set("Connection", "keep-alive")
+	// Connection-specific headers are not allowed in HTTP/2
+	if ctx.Request.ProtoMajor == 1 {
+		ctx.Response.Header().Set("Connection", "keep-alive")
+	}
 	ctx.Response.WriteHeader(http.StatusOK)
-	flusher.Flush()
+	_ = rc.Flush()
+
+	streamCtx, cancel := context.WithCancel(ctx.Request.Context())
+	defer cancel()
+	if closing, ok := ctx.Request.Context().Value(serverClosingKey).(context.Context); ok {
+		stop := context.AfterFunc(closing, cancel)
+		defer stop()
+	}
+	ctx.Request = ctx.Request.WithContext(streamCtx)
+
+	defer middleware.TrackStream(ctx.Request)()
 
 	fn(&SSEWriter{
-		w: ctx.Response,
-		f: flusher,
+		w:  ctx.Response,
+		rc: rc,
 	})
 }
+
+// canFlush reports whether w, or a ResponseWriter it wraps, can flush.
+// Wrappers are unwrapped the same way as by http.ResponseController.
+func canFlush(w http.ResponseWriter) bool {
+	for {
+		switch t := w.(type) {
+		case http.Flusher:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package si
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// certCheckInterval is how often certificate files are checked for changes
+const certCheckInterval = time.Second
+
+// TLSConfig configures TLS serving
+type TLSConfig struct {
+	// CertFile and KeyFile are PEM files. They are reloaded when they
+	// change on disk, so certificates can be rotated without a restart.
+	CertFile string
+	KeyFile  string
+
+	// Dev serves a self-signed certificate for localhost, generated on
+	// first use and cached in the user cache directory. CertFile and
+	// KeyFile are ignored. Not for production.
+	Dev bool
+
+	// ClientCAFile is a PEM bundle of CAs trusted to sign client
+	// certificates. It is added to ClientCAs.
+	ClientCAFile string
+	// ClientCAs enables mutual TLS with the given CAs
+	ClientCAs *x509.CertPool
+	// ClientAuth is the client certificate policy. Defaults to
+	// tls.RequireAndVerifyClientCert when client CAs are set.
+	ClientAuth tls.ClientAuthType
+
+	// Config is the base configuration. Certificates and client
+	// authentication are filled in from the fields above.
+	Config *tls.Config
+}
+
+// WithTLS makes the server serve HTTPS
+func WithTLS(cfg TLSConfig) Option {
+	return func(s *Server) {
+		s.tls = &cfg
+	}
+}
+
+// StartTLS starts the server with TLS using the certificate and key
+// files, and blocks until it is stopped. See TLSConfig for reloading
+// and mutual TLS.
+func (s *Server) StartTLS(certFile, keyFile string) error {
+	cfg := TLSConfig{}
+	if s.tls != nil {
+		cfg = *s.tls
+	}
+	cfg.CertFile, cfg.KeyFile, cfg.Dev = certFile, keyFile, false
+	s.tls = &cfg
+
+	return s.Start()
+}
+
+// build creates the tls.Config for serving
+func (cfg *TLSConfig) build() (*tls.Config, error) {
+	tc := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.Config != nil {
+		tc = cfg.Config.Clone()
+	}
+
+	certFile, keyFile := cfg.CertFile, cfg.KeyFile
+	if cfg.Dev {
+		var err error
+		certFile, keyFile, err = devCertificate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		reloader, err := newCertReloader(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tc.GetCertificate = reloader.GetCertificate
+	} else if len(tc.Certificates) == 0 && tc.GetCertificate == nil {
+		return nil, errors.New("si: TLS requires a certificate")
+	}
+
+	clientCAs := cfg.ClientCAs
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("si: read client CAs: %w", err)
+		}
+		if clientCAs == nil {
+			clientCAs = x509.NewCertPool()
+		} else {
+			clientCAs = clientCAs.Clone()
+		}
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("si: no certificates found in %s", cfg.ClientCAFile)
+		}
+	}
+	if clientCAs != nil {
+		tc.ClientCAs = clientCAs
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if cfg.ClientAuth != tls.NoClientCert {
+		tc.ClientAuth = cfg.ClientAuth
+	}
+
+	return tc, nil
+}
+
+// certReloader serves a certificate and reloads it when its files change
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	stamps  [2]fileStamp
+	checked time.Time
+}
+
+// fileStamp identifies a version of a file by its size and modification
+// time. Any difference counts as a change: a rotated file can be older
+// than the one it replaces, e.g. when it is copied preserving times.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+func (f fileStamp) equal(other fileStamp) bool {
+	return f.size == other.size && f.modTime.Equal(other.modTime)
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	cr := &certReloader{certFile: certFile, keyFile: keyFile}
+	stamps, err := cr.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := cr.load(stamps); err != nil {
+		return nil, err
+	}
+
+	return cr, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (cr *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.RLock()
+	cert, due := cr.cert, time.Since(cr.checked) >= certCheckInterval
+	cr.mu.RUnlock()
+
+	if due {
+		cr.reload()
+		cr.mu.RLock()
+		cert = cr.cert
+		cr.mu.RUnlock()
+	}
+
+	return cert, nil
+}
+
+// reload loads the files again if they changed. A broken pair is
+// logged and the previous certificate is kept, so a rotation written
+// in two steps doesn't take the server down.
+func (cr *certReloader) reload() {
+	cr.mu.Lock()
+	if time.Since(cr.checked) < certCheckInterval {
+		cr.mu.Unlock()
+		return
+	}
+	cr.checked = time.Now()
+	current := cr.stamps
+	cr.mu.Unlock()
+
+	stamps, err := cr.stat()
+	if err != nil || stamps[0].equal(current[0]) && stamps[1].equal(current[1]) {
+		return
+	}
+	if err := cr.load(stamps); err != nil {
+		slog.Error("failed to reload TLS certificate", "cert", cr.certFile, "error", err)
+		return
+	}
+	slog.Info("reloaded TLS certificate", "cert", cr.certFile)
+}
+
+func (cr *certReloader) load(stamps [2]fileStamp) error {
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return fmt.Errorf("si: load TLS certificate: %w", err)
+	}
+
+	cr.mu.Lock()
+	cr.cert = &cert
+	cr.stamps = stamps
+	cr.checked = time.Now()
+	cr.mu.Unlock()
+
+	return nil
+}
+
+// stat returns the stamps of the certificate and key files
+func (cr *certReloader) stat() ([2]fileStamp, error) {
+	var stamps [2]fileStamp
+	for i, name := range []string{cr.certFile, cr.keyFile} {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return stamps, fmt.Errorf("si: load TLS certificate: %w", err)
+		}
+		stamps[i] = fileStamp{size: fi.Size(), modTime: fi.ModTime()}
+	}
+
+	return stamps, nil
+}
+
+// devCertificate returns the files of a self-signed localhost
+// certificate, generating them when missing or about to expire
+func devCertificate() (certFile, keyFile string, err error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	dir = filepath.Join(dir, "si")
+	certFile = filepath.Join(dir, "dev-cert.pem")
+	keyFile = filepath.Join(dir, "dev-key.pem")
+
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		// Certificates cached by older versions were CAs; replace them
+		if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil &&
+			!leaf.IsCA && time.Until(leaf.NotAfter) > 30*24*time.Hour {
+			return certFile, keyFile, nil
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", "", fmt.Errorf("si: dev certificate: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("si: dev certificate: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", fmt.Errorf("si: dev certificate: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{Organization: []string{"si development"}, CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+		// A server leaf: trusting it can't let it sign other certificates
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  false,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return "", "", fmt.Errorf("si: dev certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", "", fmt.Errorf("si: dev certificate: %w", err)
+	}
+
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return "", "", fmt.Errorf("si: dev certificate: %w", err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		return "", "", fmt.Errorf("si: dev certificate: %w", err)
+	}
+	slog.Info("generated self-signed development certificate", "cert", certFile)
+
+	return certFile, keyFile, nil
+}
+
+// ClientCertificate returns the certificate the client presented on a
+// mutual TLS connection, or nil
+func (ctx *Context) ClientCertificate() *x509.Certificate {
+	if chain := ctx.ClientCertificateChain(); len(chain) > 0 {
+		return chain[0]
+	}
+
+	return nil
+}
+
+// ClientCertificateChain returns the certificates sent by the client,
+// leaf first
+func (ctx *Context) ClientCertificateChain() []*x509.Certificate {
+	if ctx.Request.TLS == nil {
+		return nil
+	}
+
+	return ctx.Request.TLS.PeerCertificates
+}
@@ -0,0 +1,207 @@
+package tracing
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// SpanKind describes the role of a span in a trace
+type SpanKind int
+
+// Span kinds, numbered as in OTLP
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// StatusCode is the outcome of a span, numbered as in OTLP
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+// Attribute is a key-value pair attached to a span or event. Values
+// are strings, bools, integers or floats; anything else is recorded
+// with fmt.Sprint.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Attr creates an Attribute
+func Attr(key string, value any) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Event is a timestamped annotation of a span
+type Event struct {
+	Name       string
+	Time       time.Time
+	Attributes []Attribute
+}
+
+// SpanData is a finished span as handed to exporters
+type SpanData struct {
+	Name        string
+	Kind        SpanKind
+	SpanContext SpanContext
+	// Parent is zero for root spans
+	Parent            SpanID
+	StartTime         time.Time
+	EndTime           time.Time
+	Attributes        []Attribute
+	Events            []Event
+	Status            StatusCode
+	StatusDescription string
+}
+
+// Span is a timed operation within a trace. All methods are safe to
+// call on a nil Span, so handlers don't need to check whether tracing
+// is enabled.
+type Span struct {
+	tracer    *Tracer
+	recording bool
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SpanContext returns the span's propagated context
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+
+	return s.data.SpanContext
+}
+
+// IsRecording reports whether the span will be exported
+func (s *Span) IsRecording() bool {
+	return s != nil && s.recording
+}
+
+// SetName replaces the span name
+func (s *Span) SetName(name string) {
+	s.update(func(d *SpanData) {
+		d.Name = name
+	})
+}
+
+// SetAttribute sets an attribute, replacing one with the same key
+func (s *Span) SetAttribute(key string, value any) {
+	s.update(func(d *SpanData) {
+		value = normalizeValue(value)
+		for i := range d.Attributes {
+			if d.Attributes[i].Key == key {
+				d.Attributes[i].Value = value
+				return
+			}
+		}
+		d.Attributes = append(d.Attributes, Attribute{Key: key, Value: value})
+	})
+}
+
+// AddEvent records an event at the current time
+func (s *Span) AddEvent(name string, attrs ...Attribute) {
+	s.update(func(d *SpanData) {
+		for i := range attrs {
+			attrs[i].Value = normalizeValue(attrs[i].Value)
+		}
+		d.Events = append(d.Events, Event{Name: name, Time: time.Now(), Attributes: attrs})
+	})
+}
+
+// RecordError records err as an "exception" event. It doesn't change
+// the status; use SetStatus for failures.
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+
+	s.AddEvent("exception",
+		Attr("exception.type", fmt.Sprintf("%T", err)),
+		Attr("exception.message", err.Error()),
+	)
+}
+
+// SetStatus sets the span's status
+func (s *Span) SetStatus(code StatusCode, description string) {
+	s.update(func(d *SpanData) {
+		d.Status = code
+		if code == StatusError {
+			d.StatusDescription = description
+		} else {
+			d.StatusDescription = ""
+		}
+	})
+}
+
+// End finishes the span and hands it to the exporter. Calls after the
+// first are ignored.
+func (s *Span) End() {
+	if s == nil || !s.recording {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	s.tracer.export(data)
+}
+
+// update changes the span data unless the span is not recorded or has
+// ended
+func (s *Span) update(fn func(d *SpanData)) {
+	if s == nil || !s.recording {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		fn(&s.data)
+	}
+}
+
+// normalizeValue converts an attribute value to one of the types
+// exporters understand: string, bool, int64 or float64
+func normalizeValue(v any) any {
+	switch v := v.(type) {
+	case string, bool, int64, float64:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	case error:
+		return v.Error()
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.String:
+		return rv.String()
+	}
+
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,276 @@
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Sampler decides whether a new trace is recorded. It is only asked for
+// root spans; child spans follow their parent's sampled flag.
+type Sampler func(traceID TraceID) bool
+
+// AlwaysSample records every trace
+func AlwaysSample() Sampler {
+	return func(TraceID) bool { return true }
+}
+
+// NeverSample records no new traces, while still following sampled
+// parents
+func NeverSample() Sampler {
+	return func(TraceID) bool { return false }
+}
+
+// RatioSampler records the given fraction of traces, deciding on the
+// random part of the trace ID so every service agrees
+func RatioSampler(ratio float64) Sampler {
+	if ratio >= 1 {
+		return AlwaysSample()
+	}
+	if ratio <= 0 {
+		return NeverSample()
+	}
+	bound := uint64(ratio * (1 << 56))
+
+	return func(id TraceID) bool {
+		return binary.BigEndian.Uint64(id[8:])&(1<<56-1) < bound
+	}
+}
+
+// Config configures a Tracer
+type Config struct {
+	Exporter Exporter
+	// Sampler decides which new traces are recorded. Defaults to
+	// AlwaysSample.
+	Sampler Sampler
+	// BatchSize is the number of spans exported at once. Defaults to 512.
+	BatchSize int
+	// BatchTimeout is the longest a span waits before export. Defaults
+	// to 5s.
+	BatchTimeout time.Duration
+	// QueueSize bounds the spans waiting for export; spans ending while
+	// the queue is full are dropped. Defaults to 2048.
+	QueueSize int
+	// Synchronous exports each span when it ends, which is meant for
+	// tests with an InMemoryExporter
+	Synchronous bool
+	// OnError is called when an export fails. Defaults to slog.Warn.
+	OnError func(err error)
+}
+
+// exportTimeout bounds a single batch export
+const exportTimeout = 30 * time.Second
+
+// Tracer starts spans and exports them in batches
+type Tracer struct {
+	cfg Config
+
+	queue   chan SpanData
+	flush   chan chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewTracer creates a tracer. Call Shutdown before exiting to export
+// the remaining spans.
+func NewTracer(cfg Config) *Tracer {
+	if cfg.Sampler == nil {
+		cfg.Sampler = AlwaysSample()
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 512
+	}
+	if cfg.BatchTimeout <= 0 {
+		cfg.BatchTimeout = 5 * time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 2048
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(err error) {
+			slog.Warn("tracing: export failed", "error", err)
+		}
+	}
+
+	t := &Tracer{cfg: cfg}
+	if cfg.Exporter != nil && !cfg.Synchronous {
+		t.queue = make(chan SpanData, cfg.QueueSize)
+		t.flush = make(chan chan struct{})
+		t.stop = make(chan struct{})
+		t.stopped = make(chan struct{})
+		go t.loop()
+	}
+
+	return t
+}
+
+// Start starts a span as a child of the span, or remote span context,
+// in ctx, and returns a context carrying the new span. The caller must
+// End the span.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+
+	sc := SpanContext{SpanID: newSpanID()}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.TraceState = parent.TraceState
+		sc.Flags = parent.Flags & FlagSampled
+	} else {
+		sc.TraceID = newTraceID()
+		if t.cfg.Sampler(sc.TraceID) {
+			sc.Flags = FlagSampled
+		}
+	}
+
+	span := &Span{
+		tracer:    t,
+		recording: sc.IsSampled() && t.cfg.Exporter != nil,
+		data: SpanData{
+			Name:        name,
+			Kind:        kind,
+			SpanContext: sc,
+			StartTime:   time.Now(),
+		},
+	}
+	if parent.IsValid() {
+		span.data.Parent = parent.SpanID
+	}
+	for _, a := range attrs {
+		span.SetAttribute(a.Key, a.Value)
+	}
+
+	return ContextWithSpan(ctx, span), span
+}
+
+// export queues a finished span, dropping it when the queue is full or
+// the tracer is shut down
+func (t *Tracer) export(data SpanData) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.closed {
+		return
+	}
+
+	if t.cfg.Synchronous {
+		t.exportBatch([]SpanData{data})
+		return
+	}
+
+	select {
+	case t.queue <- data:
+	default:
+	}
+}
+
+func (t *Tracer) exportBatch(batch []SpanData) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	if err := t.cfg.Exporter.ExportSpans(ctx, batch); err != nil {
+		t.cfg.OnError(err)
+	}
+}
+
+// loop collects queued spans into batches
+func (t *Tracer) loop() {
+	defer close(t.stopped)
+
+	ticker := time.NewTicker(t.cfg.BatchTimeout)
+	defer ticker.Stop()
+
+	var batch []SpanData
+	export := func() {
+		if len(batch) > 0 {
+			t.exportBatch(batch)
+			batch = nil
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case data := <-t.queue:
+				batch = append(batch, data)
+				if len(batch) >= t.cfg.BatchSize {
+					export()
+				}
+			default:
+				export()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case data := <-t.queue:
+			batch = append(batch, data)
+			if len(batch) >= t.cfg.BatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case done := <-t.flush:
+			drain()
+			close(done)
+		case <-t.stop:
+			drain()
+			return
+		}
+	}
+}
+
+// ForceFlush exports the spans ended so far
+func (t *Tracer) ForceFlush(ctx context.Context) error {
+	if t.flush == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	select {
+	case t.flush <- done:
+	case <-t.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports the remaining spans and shuts the exporter down.
+// Spans ending afterwards are dropped. Its signature matches si.Hook,
+// so it can be passed to Server.OnShutdown.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	t.mu.Unlock()
+
+	if t.stop != nil {
+		close(t.stop)
+		select {
+		case <-t.stopped:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if t.cfg.Exporter == nil {
+		return nil
+	}
+
+	return t.cfg.Exporter.Shutdown(ctx)
+}
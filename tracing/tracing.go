@@ -0,0 +1,206 @@
+// Package tracing records request traces and propagates them between
+// services with W3C Trace Context (traceparent and tracestate headers).
+// Spans are exported through an Exporter, e.g. to an OpenTelemetry
+// collector over OTLP/HTTP.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Trace Context headers
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// maxTracestateLen is the length above which tracestate is dropped
+// rather than propagated
+const maxTracestateLen = 512
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// IsValid reports whether the ID is not all zeros
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// IsValid reports whether the ID is not all zeros
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// FlagSampled is the trace flag telling that the caller records the trace
+const FlagSampled byte = 0x01
+
+// SpanContext is the part of a span that is propagated to other services
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+	// Remote is set for span contexts extracted from a request
+	Remote bool
+}
+
+// IsValid reports whether both IDs are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// IsSampled reports whether the sampled flag is set
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags&FlagSampled != 0
+}
+
+// Traceparent formats the span context as a version 00 traceparent
+func (sc SpanContext) Traceparent() string {
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{sc.Flags})
+}
+
+var errTraceparent = errors.New("tracing: invalid traceparent")
+
+// ParseTraceparent parses a traceparent header value. Versions above 00
+// are accepted as long as they start with the version 00 fields.
+func ParseTraceparent(s string) (SpanContext, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return SpanContext{}, errTraceparent
+	}
+
+	version, ok := decodeHex(s[:2], 1)
+	if !ok || version[0] == 0xff || (version[0] == 0 && len(s) != 55) || (len(s) > 55 && s[55] != '-') {
+		return SpanContext{}, errTraceparent
+	}
+
+	var sc SpanContext
+	traceID, ok1 := decodeHex(s[3:35], 16)
+	spanID, ok2 := decodeHex(s[36:52], 8)
+	flags, ok3 := decodeHex(s[53:55], 1)
+	if !ok1 || !ok2 || !ok3 {
+		return SpanContext{}, errTraceparent
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Flags = flags[0]
+	if version[0] == 0 {
+		sc.Flags &= FlagSampled
+	}
+
+	if !sc.IsValid() {
+		return SpanContext{}, errTraceparent
+	}
+
+	return sc, nil
+}
+
+// decodeHex decodes lowercase hex of n bytes
+func decodeHex(s string, n int) ([]byte, bool) {
+	if len(s) != 2*n || strings.ToLower(s) != s {
+		return nil, false
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, false
+	}
+
+	return b, true
+}
+
+// Extract reads the span context from traceparent and tracestate
+// headers. The result is invalid when traceparent is missing or
+// malformed, in which case tracestate is ignored too.
+func Extract(h http.Header) SpanContext {
+	sc, err := ParseTraceparent(h.Get(TraceparentHeader))
+	if err != nil {
+		return SpanContext{}
+	}
+	sc.Remote = true
+
+	if state := strings.Join(h.Values(TracestateHeader), ","); len(state) <= maxTracestateLen {
+		sc.TraceState = strings.TrimSpace(state)
+	}
+
+	return sc
+}
+
+// Inject writes the span context to traceparent and tracestate headers.
+// An invalid span context writes nothing.
+func Inject(sc SpanContext, h http.Header) {
+	if !sc.IsValid() {
+		return
+	}
+
+	h.Set(TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		h.Set(TracestateHeader, sc.TraceState)
+	} else {
+		h.Del(TracestateHeader)
+	}
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// ContextWithSpan returns a copy of ctx carrying the span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the current span, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteSpanContext returns a copy of ctx whose next span is
+// started as a child of the remote span context
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context of the current span,
+// or the remote span context when there is no span
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+
+	return sc
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+
+	return id
+}
@@ -0,0 +1,191 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	spanID  = "00f067aa0ba902b7"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		flags byte
+	}{
+		{"sampled", "00-" + traceID + "-" + spanID + "-01", 0x01},
+		{"not sampled", "00-" + traceID + "-" + spanID + "-00", 0x00},
+		{"unknown flags are dropped", "00-" + traceID + "-" + spanID + "-ff", 0x01},
+		{"surrounding space", "  00-" + traceID + "-" + spanID + "-01\t", 0x01},
+		{"future version", "01-" + traceID + "-" + spanID + "-01", 0x01},
+		{"future version with more fields", "cc-" + traceID + "-" + spanID + "-01-what-the-future-holds", 0x01},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := ParseTraceparent(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sc.TraceID.String() != traceID || sc.SpanID.String() != spanID {
+				t.Fatalf("IDs = %s %s", sc.TraceID, sc.SpanID)
+			}
+			if sc.Flags != tt.flags {
+				t.Fatalf("Flags = %#x, want %#x", sc.Flags, tt.flags)
+			}
+			if sc.Remote {
+				t.Fatal("Remote set by ParseTraceparent")
+			}
+		})
+	}
+}
+
+func TestParseTraceparentInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"empty", ""},
+		{"version ff", "ff-" + traceID + "-" + spanID + "-01"},
+		{"version 00 with more fields", "00-" + traceID + "-" + spanID + "-01-extra"},
+		{"future version without a separator", "01-" + traceID + "-" + spanID + "-01extra"},
+		{"zero trace ID", "00-" + strings.Repeat("0", 32) + "-" + spanID + "-01"},
+		{"zero span ID", "00-" + traceID + "-" + strings.Repeat("0", 16) + "-01"},
+		{"short trace ID", "00-" + traceID[2:] + "-" + spanID + "-01"},
+		{"long span ID", "00-" + traceID + "-" + spanID + "00-01"},
+		{"short flags", "00-" + traceID + "-" + spanID + "-1"},
+		{"uppercase trace ID", "00-" + strings.ToUpper(traceID) + "-" + spanID + "-01"},
+		{"uppercase version", "0A-" + traceID + "-" + spanID + "-01"},
+		{"uppercase flags", "00-" + traceID + "-" + spanID + "-0A"},
+		{"not hex", "00-" + traceID + "-" + spanID[:15] + "g-01"},
+		{"wrong separator", "00_" + traceID + "_" + spanID + "_01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if sc, err := ParseTraceparent(tt.in); err == nil {
+				t.Fatalf("ParseTraceparent(%q) = %+v", tt.in, sc)
+			}
+		})
+	}
+}
+
+func TestExtractInject(t *testing.T) {
+	in := http.Header{}
+	in.Set(TraceparentHeader, "00-"+traceID+"-"+spanID+"-01")
+	in.Add(TracestateHeader, "congo=t61rcWkgMzE")
+	in.Add(TracestateHeader, "rojo=00f067aa0ba902b7")
+
+	sc := Extract(in)
+	if !sc.IsValid() || !sc.Remote {
+		t.Fatalf("Extract() = %+v", sc)
+	}
+	// Repeated tracestate headers are combined
+	if sc.TraceState != "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7" {
+		t.Fatalf("TraceState = %q", sc.TraceState)
+	}
+
+	out := http.Header{}
+	Inject(sc, out)
+	if got := out.Get(TraceparentHeader); got != in.Get(TraceparentHeader) {
+		t.Fatalf("traceparent = %q", got)
+	}
+	if got := out.Get(TracestateHeader); got != sc.TraceState {
+		t.Fatalf("tracestate = %q", got)
+	}
+	if again := Extract(out); again != sc {
+		t.Fatalf("round trip = %+v, want %+v", again, sc)
+	}
+}
+
+func TestExtractIgnoresTracestate(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		tracestate  string
+		valid       bool
+	}{
+		{"missing traceparent", "", "congo=t61rcWkgMzE", false},
+		{"invalid traceparent", "00-" + traceID + "-" + spanID, "congo=t61rcWkgMzE", false},
+		{"oversized tracestate", "00-" + traceID + "-" + spanID + "-01", "a=" + strings.Repeat("x", maxTracestateLen), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			if tt.traceparent != "" {
+				h.Set(TraceparentHeader, tt.traceparent)
+			}
+			h.Set(TracestateHeader, tt.tracestate)
+
+			sc := Extract(h)
+			if sc.IsValid() != tt.valid || sc.TraceState != "" {
+				t.Fatalf("Extract() = %+v", sc)
+			}
+		})
+	}
+}
+
+func TestInject(t *testing.T) {
+	h := http.Header{}
+	h.Set(TraceparentHeader, "keep")
+	Inject(SpanContext{}, h)
+	if h.Get(TraceparentHeader) != "keep" {
+		t.Fatal("invalid span context changed the headers")
+	}
+
+	sc, _ := ParseTraceparent("00-" + traceID + "-" + spanID + "-00")
+	h.Set(TracestateHeader, "stale=1")
+	Inject(sc, h)
+	if h.Get(TraceparentHeader) != "00-"+traceID+"-"+spanID+"-00" || h.Get(TracestateHeader) != "" {
+		t.Fatalf("headers = %v", h)
+	}
+}
+
+func TestTransportPropagates(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(Config{Exporter: exporter, Synchronous: true})
+
+	parent, _ := ParseTraceparent("00-" + traceID + "-" + spanID + "-01")
+	parent.TraceState = "congo=t61rcWkgMzE"
+	ctx := ContextWithRemoteSpanContext(context.Background(), parent)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	client := &http.Client{Transport: NewTransport(tracer, nil)}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if req.Header.Get(TraceparentHeader) != "" {
+		t.Fatal("caller's request was modified")
+	}
+
+	spans := exporter.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("exported %d spans", len(spans))
+	}
+	span := spans[0]
+	if span.Parent != parent.SpanID || span.SpanContext.TraceID != parent.TraceID {
+		t.Fatalf("client span = %+v", span.SpanContext)
+	}
+
+	// The called service sees the client span as its parent
+	sc := Extract(got)
+	if sc.TraceID != parent.TraceID || sc.SpanID != span.SpanContext.SpanID || !sc.IsSampled() || sc.TraceState != parent.TraceState {
+		t.Fatalf("propagated %+v", sc)
+	}
+}
@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Transport is an http.RoundTripper that propagates the trace of the
+// request's context to the called service. With a Tracer it also
+// records a client span for each request.
+type Transport struct {
+	// Base defaults to http.DefaultTransport
+	Base http.RoundTripper
+	// Tracer records client spans. When nil, the current span context
+	// is only propagated.
+	Tracer *Tracer
+}
+
+// NewTransport wraps base, or http.DefaultTransport when nil
+func NewTransport(tracer *Tracer, base http.RoundTripper) *Transport {
+	return &Transport{Base: base, Tracer: tracer}
+}
+
+// RoundTrip implements http.RoundTripper. The client span ends when the
+// response headers arrive.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	ctx := req.Context()
+	var span *Span
+	if t.Tracer != nil {
+		ctx, span = t.Tracer.Start(ctx, req.Method, SpanKindClient,
+			Attr("http.request.method", req.Method),
+			Attr("url.full", req.URL.Redacted()),
+			Attr("server.address", req.URL.Hostname()),
+		)
+		defer span.End()
+		if port := req.URL.Port(); port != "" {
+			if n, err := strconv.Atoi(port); err == nil {
+				span.SetAttribute("server.port", n)
+			}
+		}
+	}
+
+	sc := SpanContextFromContext(ctx)
+	if sc.IsValid() {
+		// A RoundTripper must not modify the caller's request
+		req = req.Clone(ctx)
+		Inject(sc, req.Header)
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(StatusError, err.Error())
+		return nil, err
+	}
+
+	span.SetAttribute("http.response.status_code", resp.StatusCode)
+	if resp.StatusCode >= 400 {
+		span.SetStatus(StatusError, "")
+	}
+
+	return resp, nil
+}
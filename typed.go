@@ -0,0 +1,132 @@
+package si
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"runtime"
+)
+
+// TypedFunc is a handler with a typed input and output
+type TypedFunc[In, Out any] func(ctx *Context, in In) (Out, error)
+
+// typedInfo describes the input and output of a handler made by Typed
+type typedInfo struct {
+	in     reflect.Type
+	out    reflect.Type
+	status int
+}
+
+// Typed adapts fn to a HandlerFunc. The input is bound from the request
+// and validated (see Context.BindAndValidate), or decoded from the body
+// when In is not a struct. The output is encoded with Context.Respond
+// using status, which defaults to 200. An output of type struct{} is
+// sent as 204 No Content. Errors are passed to the router's ErrorHandler.
+//
+// Routes registered with a typed handler get their OpenAPI request and
+// response types from In and Out.
+func Typed[In, Out any](fn TypedFunc[In, Out], status ...int) HandlerFunc {
+	info := typedInfo{
+		in:     indirectType(reflect.TypeOf((*In)(nil)).Elem()),
+		out:    indirectType(reflect.TypeOf((*Out)(nil)).Elem()),
+		status: http.StatusOK,
+	}
+	if len(status) > 0 {
+		info.status = status[0]
+	}
+	noContent := info.out.Kind() == reflect.Struct && info.out.NumField() == 0
+	if noContent {
+		info.status = http.StatusNoContent
+		info.out = nil
+	}
+
+	return func(ctx *Context) {
+		if ctx.describe != nil {
+			*ctx.describe = info
+			return
+		}
+
+		var in In
+		if err := bindTyped(ctx, &in); err != nil {
+			ctx.Error(err)
+			return
+		}
+
+		out, err := fn(ctx, in)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+
+		if noContent {
+			ctx.WriteStatus(http.StatusNoContent)
+			return
+		}
+		ctx.Respond(out, info.status)
+	}
+}
+
+// typedFuncName is the name of the function behind every handler made
+// by Typed; instantiations differ only in the values they capture
+var typedFuncName = funcName(Typed(func(*Context, struct{}) (struct{}, error) {
+	return struct{}{}, nil
+}))
+
+func funcName(handler HandlerFunc) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()); f != nil {
+		return f.Name()
+	}
+
+	return ""
+}
+
+// bindTyped fills the input of a typed handler
+func bindTyped(ctx *Context, dst any) error {
+	rv := reflect.ValueOf(dst).Elem()
+	if rv.Kind() == reflect.Pointer {
+		rv.Set(reflect.New(rv.Type().Elem()))
+		dst = rv.Interface()
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() == reflect.Struct {
+		return ctx.BindAndValidate(dst)
+	}
+
+	body, err := ctx.GetRawContent()
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	decoder := ctx.router.decoderFor(ctx.ContentType())
+	if decoder == nil {
+		return NewHTTPError(http.StatusUnsupportedMediaType, "")
+	}
+	if err := decoder.Decode(bytes.NewReader(body), dst); err != nil {
+		return &BindError{Errors: []*FieldError{bodyFieldError(err)}}
+	}
+
+	return nil
+}
+
+// typedDoc documents the route from its handler if Typed made it. The
+// handler is called with a describing context, to which it reports its
+// types without touching the request.
+func (rt *Route) typedDoc(handler HandlerFunc) {
+	if handler == nil || typedFuncName == "" || funcName(handler) != typedFuncName {
+		return
+	}
+	var info typedInfo
+	handler(&Context{describe: &info})
+
+	if info.in.Kind() != reflect.Struct || info.in.NumField() > 0 {
+		rt.doc.request = info.in
+	}
+	rt.doc.responses = append(rt.doc.responses, routeResponse{
+		status: info.status,
+		body:   info.out,
+	})
+}
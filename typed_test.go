@@ -0,0 +1,87 @@
+package si
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type typedUser struct {
+	ID   int    `path:"id"`
+	Name string `json:"name" validate:"required"`
+}
+
+type typedOrg struct {
+	Title string `json:"title"`
+}
+
+// echo returns handlers made from the same function literal
+func echo[T any]() HandlerFunc {
+	return Typed(func(ctx *Context, in T) (T, error) { return in, nil }, http.StatusCreated)
+}
+
+func TestTypedDocumentsEachRoute(t *testing.T) {
+	r := NewRouter()
+	r.Post("/users/{id}", echo[typedUser]())
+	r.With().Post("/orgs", echo[typedOrg]())
+	r.Route("/teams", func(teams *Router) {
+		teams.Put("/", echo[[]string]())
+	})
+	r.Delete("/orgs", Typed(func(ctx *Context, in struct{}) (struct{}, error) {
+		return struct{}{}, nil
+	}))
+	r.Get("/plain", func(ctx *Context) {
+		t.Error("plain handler called while registering")
+	})
+
+	doc := r.OpenAPI(OpenAPIConfig{})
+	users := doc.Paths["/users/{id}"].Post
+	orgs := doc.Paths["/orgs"].Post
+	if users == nil || orgs == nil {
+		t.Fatalf("missing operations in %v", doc.Paths)
+	}
+	if users.RequestBody == nil || users.Responses["201"] == nil {
+		t.Fatalf("users operation not documented from its types: %+v", users)
+	}
+	userSchema := users.Responses["201"].Content["application/json"].Schema
+	orgSchema := orgs.Responses["201"].Content["application/json"].Schema
+	if userSchema.Ref == orgSchema.Ref {
+		t.Fatalf("both routes documented with %q", userSchema.Ref)
+	}
+
+	teams := doc.Paths["/teams"].Put
+	if teams == nil || !teams.RequestBody.Content["application/json"].Schema.Type.Has("array") {
+		t.Fatalf("teams operation = %+v", teams)
+	}
+
+	del := doc.Paths["/orgs"].Delete
+	if del.RequestBody != nil || del.Responses["204"] == nil || del.Responses["204"].Content != nil {
+		t.Fatalf("struct{} handler documented as %+v", del)
+	}
+}
+
+func TestTypedServesRequests(t *testing.T) {
+	r := NewRouter()
+	r.Post("/users/{id}", echo[typedUser]())
+
+	tests := []struct {
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{`{"name":"ann"}`, http.StatusCreated, `"name":"ann"`},
+		{`{}`, http.StatusUnprocessableEntity, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/users/7", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.chi.ServeHTTP(w, req)
+
+		if w.Code != tt.wantCode || !strings.Contains(w.Body.String(), tt.wantBody) {
+			t.Errorf("%s: got %d %s, want %d", tt.body, w.Code, w.Body, tt.wantCode)
+		}
+	}
+}
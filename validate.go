@@ -0,0 +1,479 @@
+package si
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationFunc reports whether field satisfies a rule.
+// param is the text after "=" in the tag, parent is the struct holding
+// the field and can be used by cross-field rules.
+type ValidationFunc func(field reflect.Value, param string, parent reflect.Value) bool
+
+// ValidationError describes a single failed rule
+type ValidationError struct {
+	Field   string
+	Rule    string
+	Param   string
+	Message string
+}
+
+// Error implements the error interface
+func (e ValidationError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// ValidationErrors is returned by Validate and lists every failed rule.
+// DefaultErrorHandler renders it as a 422 response.
+type ValidationErrors []ValidationError
+
+// Error implements the error interface
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, ve := range e {
+		msgs[i] = ve.Error()
+	}
+
+	return "validation: " + strings.Join(msgs, "; ")
+}
+
+// Fields returns the error messages keyed by field name
+func (e ValidationErrors) Fields() map[string][]string {
+	fields := map[string][]string{}
+	for _, ve := range e {
+		fields[ve.Field] = append(fields[ve.Field], ve.Message)
+	}
+
+	return fields
+}
+
+// Details returns a JSON-friendly description of the failed rules
+func (e ValidationErrors) Details() []Map {
+	details := make([]Map, len(e))
+	for i, ve := range e {
+		details[i] = Map{
+			"field":   ve.Field,
+			"rule":    ve.Rule,
+			"message": ve.Message,
+		}
+	}
+
+	return details
+}
+
+var (
+	uuidRegexp     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	alphaRegexp    = regexp.MustCompile(`^[a-zA-Z]+$`)
+	alphanumRegexp = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+	numericRegexp  = regexp.MustCompile(`^[-+]?[0-9]+(\.[0-9]+)?$`)
+)
+
+// validationRules holds the built-in rules. Rules registered on a Router
+// take precedence.
+var validationRules = map[string]ValidationFunc{
+	"required": func(field reflect.Value, _ string, _ reflect.Value) bool {
+		return !field.IsZero()
+	},
+	"min": func(field reflect.Value, param string, _ reflect.Value) bool {
+		cmp, err := compareSize(field, param)
+		return err == nil && cmp >= 0
+	},
+	"max": func(field reflect.Value, param string, _ reflect.Value) bool {
+		cmp, err := compareSize(field, param)
+		return err == nil && cmp <= 0
+	},
+	"len": func(field reflect.Value, param string, _ reflect.Value) bool {
+		cmp, err := compareSize(field, param)
+		return err == nil && cmp == 0
+	},
+	"gt": func(field reflect.Value, param string, _ reflect.Value) bool {
+		cmp, err := compareSize(field, param)
+		return err == nil && cmp > 0
+	},
+	"lt": func(field reflect.Value, param string, _ reflect.Value) bool {
+		cmp, err := compareSize(field, param)
+		return err == nil && cmp < 0
+	},
+	"oneof": func(field reflect.Value, param string, _ reflect.Value) bool {
+		value := fmt.Sprint(field.Interface())
+		for _, option := range strings.Fields(param) {
+			if value == option {
+				return true
+			}
+		}
+		return false
+	},
+	"email": func(field reflect.Value, _ string, _ reflect.Value) bool {
+		addr, err := mail.ParseAddress(field.String())
+		return err == nil && addr.Address == field.String()
+	},
+	"uuid": func(field reflect.Value, _ string, _ reflect.Value) bool {
+		return uuidRegexp.MatchString(field.String())
+	},
+	"url": func(field reflect.Value, _ string, _ reflect.Value) bool {
+		u, err := url.Parse(field.String())
+		return err == nil && u.Scheme != "" && u.Host != ""
+	},
+	"alpha": func(field reflect.Value, _ string, _ reflect.Value) bool {
+		return alphaRegexp.MatchString(field.String())
+	},
+	"alphanum": func(field reflect.Value, _ string, _ reflect.Value) bool {
+		return alphanumRegexp.MatchString(field.String())
+	},
+	"numeric": func(field reflect.Value, _ string, _ reflect.Value) bool {
+		return numericRegexp.MatchString(field.String())
+	},
+	"eqfield": func(field reflect.Value, param string, parent reflect.Value) bool {
+		return equalFields(field, parent.FieldByName(param))
+	},
+	"nefield": func(field reflect.Value, param string, parent reflect.Value) bool {
+		return !equalFields(field, parent.FieldByName(param))
+	},
+	"gtfield": func(field reflect.Value, param string, parent reflect.Value) bool {
+		cmp, ok := compareFields(field, parent.FieldByName(param))
+		return ok && cmp > 0
+	},
+	"gtefield": func(field reflect.Value, param string, parent reflect.Value) bool {
+		cmp, ok := compareFields(field, parent.FieldByName(param))
+		return ok && cmp >= 0
+	},
+	"ltfield": func(field reflect.Value, param string, parent reflect.Value) bool {
+		cmp, ok := compareFields(field, parent.FieldByName(param))
+		return ok && cmp < 0
+	},
+	"ltefield": func(field reflect.Value, param string, parent reflect.Value) bool {
+		cmp, ok := compareFields(field, parent.FieldByName(param))
+		return ok && cmp <= 0
+	},
+	"required_with": func(field reflect.Value, param string, parent reflect.Value) bool {
+		other := parent.FieldByName(param)
+		return !other.IsValid() || other.IsZero() || !field.IsZero()
+	},
+	"required_without": func(field reflect.Value, param string, parent reflect.Value) bool {
+		other := parent.FieldByName(param)
+		return (other.IsValid() && !other.IsZero()) || !field.IsZero()
+	},
+}
+
+// validationMessages holds the messages for built-in rules.
+// %s is replaced with the rule parameter.
+var validationMessages = map[string]string{
+	"required":         "is required",
+	"min":              "must be at least %s",
+	"max":              "must be at most %s",
+	"len":              "must have length %s",
+	"gt":               "must be greater than %s",
+	"lt":               "must be less than %s",
+	"oneof":            "must be one of: %s",
+	"email":            "must be a valid email address",
+	"uuid":             "must be a valid UUID",
+	"url":              "must be a valid URL",
+	"alpha":            "must contain only letters",
+	"alphanum":         "must contain only letters and digits",
+	"numeric":          "must be numeric",
+	"eqfield":          "must be equal to %s",
+	"nefield":          "must not be equal to %s",
+	"gtfield":          "must be greater than %s",
+	"gtefield":         "must be greater than or equal to %s",
+	"ltfield":          "must be less than %s",
+	"ltefield":         "must be less than or equal to %s",
+	"required_with":    "is required when %s is present",
+	"required_without": "is required when %s is absent",
+}
+
+// RegisterValidation adds a named rule usable in `validate` tags of
+// structs validated by handlers on this router and its subrouters.
+func (r *Router) RegisterValidation(name string, fn ValidationFunc) {
+	if r.validators == nil {
+		r.validators = map[string]ValidationFunc{}
+	}
+	r.validators[name] = fn
+}
+
+// validationRule returns the closest rule with the given name in the
+// router chain, falling back to the built-in rules.
+func (r *Router) validationRule(name string) (fn ValidationFunc, builtin, ok bool) {
+	for ; r != nil; r = r.parent {
+		if fn, ok := r.validators[name]; ok {
+			return fn, false, true
+		}
+	}
+
+	fn, ok = validationRules[name]
+	return fn, true, ok
+}
+
+// checkRuleParam reports a parameter a built-in rule can't use. It is a
+// mistake in the struct tag, so it fails the request with an error
+// instead of a validation message.
+func checkRuleParam(rule, param string, parent reflect.Value) error {
+	switch rule {
+	case "min", "max", "len", "gt", "lt":
+		if _, err := strconv.ParseFloat(param, 64); err != nil {
+			return fmt.Errorf("parameter %q is not a number", param)
+		}
+	case "eqfield", "nefield", "gtfield", "gtefield", "ltfield", "ltefield", "required_with", "required_without":
+		if !parent.FieldByName(param).IsValid() {
+			return fmt.Errorf("no field %q in %s", param, parent.Type())
+		}
+	}
+
+	return nil
+}
+
+// Validate checks v against its `validate` struct tags:
+//
+//	type SignUp struct {
+//		Email    string `json:"email" validate:"required,email"`
+//		Password string `json:"password" validate:"required,min=8"`
+//		Confirm  string `json:"confirm" validate:"eqfield=Password"`
+//		Role     string `json:"role" validate:"omitempty,oneof=admin user"`
+//	}
+//
+// Rules are separated by commas; parameters follow "=". "omitempty"
+// skips the remaining rules when the field is empty. Nested structs are
+// validated recursively. Failed rules are collected into ValidationErrors.
+func (ctx *Context) Validate(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return errors.New("si: Validate requires a non-nil struct")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return errors.New("si: Validate requires a struct")
+	}
+
+	var errs ValidationErrors
+	if err := ctx.validateStruct(rv, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// BindAndValidate binds the request into dst and validates the result
+func (ctx *Context) BindAndValidate(dst any) error {
+	if err := ctx.Bind(dst); err != nil {
+		return err
+	}
+
+	return ctx.Validate(dst)
+}
+
+func (ctx *Context) validateStruct(v reflect.Value, prefix string, errs *ValidationErrors) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		fv := v.Field(i)
+		name := prefix + fieldName(sf)
+
+		if tag := sf.Tag.Get("validate"); tag != "" && tag != "-" {
+			if err := ctx.validateField(fv, v, name, tag, errs); err != nil {
+				return err
+			}
+		}
+
+		// Descend into nested structs
+		nested := fv
+		for nested.Kind() == reflect.Pointer && !nested.IsNil() {
+			nested = nested.Elem()
+		}
+		if nested.Kind() == reflect.Struct && nested.Type() != timeType {
+			if sf.Anonymous {
+				name = prefix
+			} else {
+				name += "."
+			}
+			if err := ctx.validateStruct(nested, name, errs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (ctx *Context) validateField(field, parent reflect.Value, name, tag string, errs *ValidationErrors) error {
+	for _, rule := range strings.Split(tag, ",") {
+		rule, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if rule == "" {
+			continue
+		}
+
+		if rule == "omitempty" {
+			if field.IsZero() {
+				return nil
+			}
+			continue
+		}
+
+		fn, builtin, ok := ctx.router.validationRule(rule)
+		if !ok {
+			return fmt.Errorf("si: unknown validation rule %q on field %s", rule, name)
+		}
+		if builtin {
+			if err := checkRuleParam(rule, param, parent); err != nil {
+				return fmt.Errorf("si: validation rule %q on field %s: %w", rule, name, err)
+			}
+		}
+
+		value := field
+		if rule != "required" {
+			for value.Kind() == reflect.Pointer {
+				if value.IsNil() {
+					break
+				}
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Pointer {
+				// nil pointers are only checked by "required"
+				continue
+			}
+		}
+
+		if !fn(value, param, parent) {
+			*errs = append(*errs, ValidationError{
+				Field:   name,
+				Rule:    rule,
+				Param:   param,
+				Message: validationMessage(rule, param),
+			})
+		}
+	}
+
+	return nil
+}
+
+func validationMessage(rule, param string) string {
+	msg, ok := validationMessages[rule]
+	if !ok {
+		return "failed " + rule + " validation"
+	}
+	if strings.Contains(msg, "%s") {
+		return fmt.Sprintf(msg, param)
+	}
+
+	return msg
+}
+
+// fieldName returns the name used for a field in error reports:
+// the json tag, a binding tag, or the Go field name.
+func fieldName(sf reflect.StructField) string {
+	for _, tag := range append([]string{"json"}, bindSources...) {
+		name, _, _ := strings.Cut(sf.Tag.Get(tag), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+
+	return sf.Name
+}
+
+// compareSize compares a value against param: numbers by value,
+// strings by rune count, slices, maps and arrays by length.
+func compareSize(field reflect.Value, param string) (int, error) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parameter %q is not a number", param)
+	}
+
+	var n float64
+	switch field.Kind() {
+	case reflect.String:
+		n = float64(utf8.RuneCountInString(field.String()))
+	case reflect.Slice, reflect.Map, reflect.Array:
+		n = float64(field.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(field.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(field.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = field.Float()
+	default:
+		return 0, nil
+	}
+
+	switch {
+	case n < limit:
+		return -1, nil
+	case n > limit:
+		return 1, nil
+	}
+
+	return 0, nil
+}
+
+// equalFields reports whether two values are deeply equal,
+// ignoring pointer indirection.
+func equalFields(a, b reflect.Value) bool {
+	if !b.IsValid() {
+		return false
+	}
+	a, b = reflect.Indirect(a), reflect.Indirect(b)
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// compareFields orders two values of the same kind.
+// ok is false when the values cannot be ordered.
+func compareFields(a, b reflect.Value) (cmp int, ok bool) {
+	if !b.IsValid() {
+		return 0, false
+	}
+	a, b = reflect.Indirect(a), reflect.Indirect(b)
+	if !a.IsValid() || !b.IsValid() {
+		return 0, false
+	}
+
+	if a.Type() == timeType && b.Type() == timeType {
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time)), true
+	}
+
+	if a.Kind() != b.Kind() {
+		return 0, false
+	}
+
+	switch a.Kind() {
+	case reflect.String:
+		return strings.Compare(a.String(), b.String()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return sign(float64(a.Int()) - float64(b.Int())), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return sign(float64(a.Uint()) - float64(b.Uint())), true
+	case reflect.Float32, reflect.Float64:
+		return sign(a.Float() - b.Float()), true
+	}
+
+	return 0, false
+}
+
+func sign(f float64) int {
+	switch {
+	case f < 0:
+		return -1
+	case f > 0:
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,155 @@
+package si
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func validateCtx() *Context {
+	return Si(httptest.NewRequest("GET", "/", nil), httptest.NewRecorder())
+}
+
+type signUp struct {
+	Email    string   `json:"email" validate:"required,email"`
+	Password string   `json:"password" validate:"required,min=8,max=16"`
+	Confirm  string   `json:"confirm" validate:"eqfield=Password"`
+	Role     string   `json:"role" validate:"omitempty,oneof=admin user"`
+	Age      int      `json:"age" validate:"omitempty,gt=17,lt=130"`
+	Tags     []string `json:"tags" validate:"max=2"`
+	Code     string   `json:"code" validate:"omitempty,len=4,numeric"`
+	Website  *string  `json:"website" validate:"omitempty,url"`
+	ID       string   `json:"id" validate:"omitempty,uuid"`
+	Nick     string   `json:"nick" validate:"omitempty,alphanum"`
+	Address  struct {
+		City string `json:"city" validate:"required,alpha"`
+	} `json:"address"`
+}
+
+func validSignUp() signUp {
+	s := signUp{Email: "ann@example.com", Password: "secret123", Confirm: "secret123"}
+	s.Address.City = "Oslo"
+	return s
+}
+
+func TestValidateRules(t *testing.T) {
+	site := "example.com"
+
+	tests := []struct {
+		name   string
+		modify func(*signUp)
+		want   []string // field:rule
+	}{
+		{"valid", func(*signUp) {}, nil},
+		{"required", func(s *signUp) { s.Email, s.Password = "", "" }, []string{"email:required", "email:email", "password:required", "password:min", "confirm:eqfield"}},
+		{"format", func(s *signUp) { s.Email = "Ann <ann@example.com>" }, []string{"email:email"}},
+		{"string length in runes", func(s *signUp) { s.Password, s.Confirm = "пароль", "пароль" }, []string{"password:min"}},
+		{"too long", func(s *signUp) { s.Password = strings.Repeat("x", 17); s.Confirm = s.Password }, []string{"password:max"}},
+		{"eqfield", func(s *signUp) { s.Confirm = "other" }, []string{"confirm:eqfield"}},
+		{"oneof", func(s *signUp) { s.Role = "root" }, []string{"role:oneof"}},
+		{"number bounds", func(s *signUp) { s.Age = 17 }, []string{"age:gt"}},
+		{"slice length", func(s *signUp) { s.Tags = []string{"a", "b", "c"} }, []string{"tags:max"}},
+		{"len and numeric", func(s *signUp) { s.Code = "12a" }, []string{"code:len", "code:numeric"}},
+		{"pointer", func(s *signUp) { s.Website = &site }, []string{"website:url"}},
+		{"uuid", func(s *signUp) { s.ID = "123" }, []string{"id:uuid"}},
+		{"alphanum", func(s *signUp) { s.Nick = "a-b" }, []string{"nick:alphanum"}},
+		{"nested", func(s *signUp) { s.Address.City = "" }, []string{"address.city:required", "address.city:alpha"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := validSignUp()
+			tt.modify(&s)
+
+			err := validateCtx().Validate(&s)
+			var got []string
+			var errs ValidationErrors
+			if errors.As(err, &errs) {
+				for _, e := range errs {
+					got = append(got, e.Field+":"+e.Rule)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCrossFieldOrder(t *testing.T) {
+	type period struct {
+		From  time.Time `validate:"required"`
+		To    time.Time `validate:"gtfield=From"`
+		Min   int
+		Max   int    `validate:"gtefield=Min"`
+		Phone string `validate:"required_without=Email"`
+		Email string
+	}
+
+	now := time.Now()
+	err := validateCtx().Validate(period{From: now, To: now.Add(-time.Hour), Min: 5, Max: 5})
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Validate() = %v", err)
+	}
+	fields := errs.Fields()
+	if len(errs) != 2 || fields["To"] == nil || fields["Phone"] == nil {
+		t.Fatalf("got %v", errs)
+	}
+}
+
+func TestValidateTagMistakes(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"unknown rule", &struct {
+			A string `validate:"requird"`
+		}{}, `unknown validation rule "requird"`},
+		{"non-numeric size", &struct {
+			A string `validate:"min=abc"`
+		}{A: "x"}, `parameter "abc" is not a number`},
+		{"missing field", &struct {
+			A string `validate:"eqfield=B"`
+		}{}, `no field "B"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCtx().Validate(tt.v)
+			var errs ValidationErrors
+			if err == nil || errors.As(err, &errs) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Validate() = %v, want an error mentioning %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCustomRule(t *testing.T) {
+	r := NewRouter()
+	r.RegisterValidation("even", func(field reflect.Value, _ string, _ reflect.Value) bool {
+		return field.Int()%2 == 0
+	})
+	// A custom rule named like a built-in one may take any parameter
+	r.RegisterValidation("min", func(field reflect.Value, param string, _ reflect.Value) bool {
+		return param == "any"
+	})
+
+	ctx := validateCtx()
+	ctx.router = r
+	err := ctx.Validate(struct {
+		N int `validate:"even,min=any"`
+	}{N: 3})
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Rule != "even" || errs[0].Message != "failed even validation" {
+		t.Fatalf("Validate() = %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package si
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// yamlKeyRegexp matches keys that can be written without quotes
+var yamlKeyRegexp = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_.$-]*$`)
+
+// yamlReserved are plain scalars YAML 1.1 parsers read as non-strings
+var yamlReserved = map[string]bool{
+	"true": true, "false": true, "yes": true, "no": true, "on": true,
+	"off": true, "y": true, "n": true, "null": true,
+}
+
+// yamlNode is a JSON value with object key order preserved
+type yamlNode struct {
+	object bool
+	array  bool
+	keys   []string
+	items  []*yamlNode
+	scalar string
+}
+
+// jsonToYAML converts a JSON document to block-style YAML, keeping the
+// key order of the input. Strings are written as double-quoted scalars,
+// whose escapes are compatible with JSON.
+func jsonToYAML(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	node, err := readYAMLNode(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	writeYAMLNode(buf, node, 0)
+
+	return buf.Bytes(), nil
+}
+
+func readYAMLNode(dec *json.Decoder) (*yamlNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		node := &yamlNode{object: t == '{', array: t == '['}
+		for dec.More() {
+			if node.object {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key, ok := keyTok.(string)
+				if !ok {
+					return nil, errors.New("yaml: invalid object key")
+				}
+				node.keys = append(node.keys, key)
+			}
+			item, err := readYAMLNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.items = append(node.items, item)
+		}
+		if _, err := dec.Token(); err != nil && err != io.EOF {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		b, _ := json.Marshal(t)
+		return &yamlNode{scalar: string(b)}, nil
+	case json.Number:
+		return &yamlNode{scalar: t.String()}, nil
+	case bool:
+		if t {
+			return &yamlNode{scalar: "true"}, nil
+		}
+		return &yamlNode{scalar: "false"}, nil
+	case nil:
+		return &yamlNode{scalar: "null"}, nil
+	}
+
+	return nil, errors.New("yaml: unexpected token")
+}
+
+// isCollection reports whether node is written as a nested block
+func (n *yamlNode) isCollection() bool {
+	return (n.object || n.array) && len(n.items) > 0
+}
+
+func (n *yamlNode) inline() string {
+	switch {
+	case n.object:
+		return "{}"
+	case n.array:
+		return "[]"
+	}
+
+	return n.scalar
+}
+
+func writeYAMLNode(buf *bytes.Buffer, n *yamlNode, indent int) {
+	pad := strings.Repeat("  ", indent)
+
+	if !n.isCollection() {
+		buf.WriteString(pad + n.inline() + "\n")
+		return
+	}
+
+	for i, item := range n.items {
+		if n.object {
+			key := n.keys[i]
+			if !yamlKeyRegexp.MatchString(key) || yamlReserved[strings.ToLower(key)] {
+				b, _ := json.Marshal(key)
+				key = string(b)
+			}
+			if item.isCollection() {
+				buf.WriteString(pad + key + ":\n")
+				writeYAMLNode(buf, item, indent+1)
+			} else {
+				buf.WriteString(pad + key + ": " + item.inline() + "\n")
+			}
+			continue
+		}
+
+		// Array item: nested collections start on the dash line
+		if !item.isCollection() {
+			buf.WriteString(pad + "- " + item.inline() + "\n")
+			continue
+		}
+		nested := &bytes.Buffer{}
+		writeYAMLNode(nested, item, indent+1)
+		lines := strings.TrimPrefix(nested.String(), pad+"  ")
+		buf.WriteString(pad + "- " + lines)
+	}
+}